@@ -0,0 +1,289 @@
+package main
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// An account owns the session token it was created with, its results and
+// groups stay under it. That token never goes back to a browser: every login
+// gets its own token in logins, revoked on logout, on the next login from the
+// same browser or after loginTTL.
+type account struct {
+	Username string
+	Hash     []byte
+	Token    string
+}
+
+type accountlogin struct {
+	Owner   string
+	Expires time.Time
+}
+
+const loginTTL = 30 * 24 * time.Hour
+
+type modestats struct {
+	Mode      string
+	Played    int
+	Won       int
+	WinRate   int
+	Tries     float64
+	Streak    int
+	MaxStreak int
+}
+
+var accounts = make(map[string]*account)
+var logins = make(map[string]accountlogin)
+var accountsMu sync.Mutex
+
+func loadAccounts() {
+	if a, err := pick[map[string]*account]("accounts"); err == nil {
+		accounts = a
+	}
+	if l, err := pick[map[string]accountlogin]("logins"); err == nil {
+		logins = l
+	}
+}
+
+// newLogin gives a browser a token of its own for the account.
+func newLogin(owner string) string {
+	accountsMu.Lock()
+	defer accountsMu.Unlock()
+	token := newSessionToken()
+	logins[token] = accountlogin{Owner: owner, Expires: time.Now().Add(loginTTL)}
+	dump("logins", logins)
+	return token
+}
+
+func logout(token string) {
+	accountsMu.Lock()
+	defer accountsMu.Unlock()
+	if _, ok := logins[token]; ok {
+		delete(logins, token)
+		dump("logins", logins)
+	}
+}
+
+// resolveSession gives the token a cookie plays under: the account of a
+// login, or the cookie itself for anonymous players. The token an account
+// was created with isn't accepted from a browser.
+func resolveSession(cookie string) (string, bool) {
+	accountsMu.Lock()
+	defer accountsMu.Unlock()
+	if l, ok := logins[cookie]; ok {
+		if time.Now().After(l.Expires) {
+			delete(logins, cookie)
+			dump("logins", logins)
+			return "", false
+		}
+		return l.Owner, true
+	}
+	if _, ok := getAccountByToken(cookie); ok {
+		return "", false
+	}
+	return cookie, true
+}
+
+// expireLogins drops the logins past their TTL that were never used again.
+func expireLogins() {
+	accountsMu.Lock()
+	defer accountsMu.Unlock()
+	expired := false
+	for token, l := range logins {
+		if time.Now().After(l.Expires) {
+			delete(logins, token)
+			expired = true
+		}
+	}
+	if expired {
+		dump("logins", logins)
+	}
+}
+
+func signup(username, password, token string) (*account, error) {
+	username = strings.ToLower(strings.TrimSpace(username))
+	if username == "" || len(password) < 8 {
+		return nil, errors.New("Pick a username and a password of at least 8 characters")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	accountsMu.Lock()
+	defer accountsMu.Unlock()
+	if _, ok := accounts[username]; ok {
+		return nil, errors.New("This username is already taken")
+	}
+	if _, ok := getAccountByToken(token); ok {
+		return nil, errors.New("You are already logged in")
+	}
+	a := &account{Username: username, Hash: hash, Token: token}
+	accounts[username] = a
+	dump("accounts", accounts)
+	return a, nil
+}
+
+func login(username, password string) (*account, error) {
+	accountsMu.Lock()
+	defer accountsMu.Unlock()
+	a, ok := accounts[strings.ToLower(strings.TrimSpace(username))]
+	if !ok || bcrypt.CompareHashAndPassword(a.Hash, []byte(password)) != nil {
+		return nil, errors.New("Wrong username or password")
+	}
+	return a, nil
+}
+
+func getAccount(token string) (account, bool) {
+	accountsMu.Lock()
+	defer accountsMu.Unlock()
+	a, ok := getAccountByToken(token)
+	if !ok {
+		return account{}, false
+	}
+	return *a, true
+}
+
+func getAccountTokens() map[string]bool {
+	accountsMu.Lock()
+	defer accountsMu.Unlock()
+	ret := make(map[string]bool)
+	for _, a := range accounts {
+		ret[a.Token] = true
+	}
+	return ret
+}
+
+// getAccountByToken must be called with accountsMu held.
+func getAccountByToken(token string) (*account, bool) {
+	for _, a := range accounts {
+		if a.Token == token {
+			return a, true
+		}
+	}
+	return nil, false
+}
+
+// mergeSession moves the results, groups and running games of an anonymous
+// session to an account. Games the account already has for today win.
+func mergeSession(from, to string) {
+	if from == to {
+		return
+	}
+	groupsMu.Lock()
+	for i := range results {
+		if results[i].Token == from {
+			results[i].Token = to
+		}
+	}
+	resultsChanged = true
+	for _, g := range groups {
+		if nickname, ok := g.Members[from]; ok {
+			delete(g.Members, from)
+			if _, ok := g.Members[to]; !ok {
+				g.Members[to] = nickname
+			}
+		}
+	}
+	dump("groups", groups)
+	groupsMu.Unlock()
+
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	if games, ok := sessions[from]; ok {
+		if sessions[to] == nil {
+			sessions[to] = make(map[string]*game)
+		}
+		for mode, g := range games {
+			if cur, ok := sessions[to][mode]; !ok || cur.puzzle != g.puzzle {
+				sessions[to][mode] = g
+			}
+		}
+		delete(sessions, from)
+	}
+}
+
+// getProfile sums up the results of a session for every mode it played. A
+// streak is a run of consecutive days won, the current one is still alive if
+// today isn't played yet and yesterday was won.
+func getProfile(token string) []modestats {
+	groupsMu.Lock()
+	defer groupsMu.Unlock()
+	byMode := make(map[string]*modestats)
+	tries := make(map[string]int)
+	days := make(map[string]map[string]bool)
+	for _, r := range results {
+		if r.Token != token {
+			continue
+		}
+		s, ok := byMode[r.Mode]
+		if !ok {
+			s = &modestats{Mode: r.Mode}
+			byMode[r.Mode] = s
+			days[r.Mode] = make(map[string]bool)
+		}
+		s.Played++
+		// Results from before the day was kept only have their time.
+		day := r.Day
+		if day.IsZero() {
+			day = getPuzzleDate(r.At)
+		}
+		key := day.Format("2006-01-02")
+		days[r.Mode][key] = days[r.Mode][key] || r.Won
+		if r.Won {
+			s.Won++
+			tries[r.Mode] += r.Tries
+		}
+	}
+	var ret []modestats
+	for mode, s := range byMode {
+		s.WinRate = s.Won * 100 / s.Played
+		if s.Won > 0 {
+			s.Tries = float64(tries[mode]) / float64(s.Won)
+		}
+		s.Streak, s.MaxStreak = getStreaks(days[mode], getToday())
+		ret = append(ret, *s)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Mode < ret[j].Mode })
+	return ret
+}
+
+// getStreaks takes the days played, true when won. A lost day ends the
+// current streak right away.
+func getStreaks(days map[string]bool, today time.Time) (int, int) {
+	var sorted []string
+	for d, won := range days {
+		if won {
+			sorted = append(sorted, d)
+		}
+	}
+	sort.Strings(sorted)
+	best, run := 0, 0
+	var previous time.Time
+	for _, d := range sorted {
+		day, _ := time.Parse("2006-01-02", d)
+		if run > 0 && day.Equal(previous.AddDate(0, 0, 1)) {
+			run++
+		} else {
+			run = 1
+		}
+		if run > best {
+			best = run
+		}
+		previous = day
+	}
+	current := 0
+	day := today
+	if _, played := days[day.Format("2006-01-02")]; !played {
+		day = day.AddDate(0, 0, -1)
+	}
+	for days[day.Format("2006-01-02")] {
+		current++
+		day = day.AddDate(0, 0, -1)
+	}
+	return current, best
+}
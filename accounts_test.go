@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetStreaks(t *testing.T) {
+	today := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		days    map[string]bool
+		current int
+		best    int
+	}{
+		{"nothing played", nil, 0, 0},
+		{"won today", map[string]bool{"2026-10-19": true}, 1, 1},
+		{"won yesterday, today not played yet", map[string]bool{"2026-10-17": true, "2026-10-18": true}, 2, 2},
+		{"lost today", map[string]bool{"2026-10-17": true, "2026-10-18": true, "2026-10-19": false}, 0, 2},
+		{"lost yesterday", map[string]bool{"2026-10-17": true, "2026-10-18": false}, 0, 1},
+		{"a lost day splits the runs", map[string]bool{"2026-10-10": true, "2026-10-11": true, "2026-10-12": true, "2026-10-13": false, "2026-10-14": true, "2026-10-19": true}, 1, 3},
+		{"a missed day splits the runs", map[string]bool{"2026-10-15": true, "2026-10-16": true, "2026-10-18": true, "2026-10-19": true}, 2, 2},
+		{"across a month", map[string]bool{"2026-09-30": true, "2026-10-01": true}, 0, 2},
+	}
+	for _, tt := range tests {
+		current, best := getStreaks(tt.days, today)
+		if current != tt.current || best != tt.best {
+			t.Errorf("%s: got a streak of %d and a best of %d, want %d and %d", tt.name, current, best, tt.current, tt.best)
+		}
+	}
+}
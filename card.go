@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	imagecolor "image/color"
+	"image/draw"
+	"image/png"
+	"strconv"
+	"strings"
+)
+
+// Cards use the size link previews expect and the colours of the website.
+const (
+	cardWidth  = 1200
+	cardHeight = 630
+)
+
+var cardColors = map[color]string{
+	GREEN:  "#3CB043",
+	YELLOW: "#FD8D14",
+	RED:    "#C51605",
+}
+
+func parseHexColor(hex string) imagecolor.RGBA {
+	v, _ := strconv.ParseUint(strings.TrimPrefix(hex, "#"), 16, 32)
+	return imagecolor.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 255}
+}
+
+func fillRect(img *image.RGBA, x, y, w, h int, c imagecolor.Color) {
+	draw.Draw(img, image.Rect(x, y, x+w, y+h), &image.Uniform{c}, image.Point{}, draw.Src)
+}
+
+func getTextWidth(s string, scale int) int {
+	if len(s) == 0 {
+		return 0
+	}
+	return (len(s)*6 - 1) * scale
+}
+
+func drawText(img *image.RGBA, s string, x, y, scale int, c imagecolor.Color) {
+	for _, r := range strings.ToUpper(s) {
+		if g, ok := glyphs[r]; ok {
+			for row, line := range g {
+				for col, dot := range line {
+					if dot == '#' {
+						fillRect(img, x+col*scale, y+row*scale, scale, scale, c)
+					}
+				}
+			}
+		}
+		x += 6 * scale
+	}
+}
+
+func drawCenteredText(img *image.RGBA, s string, y, scale int, c imagecolor.Color) {
+	drawText(img, s, (cardWidth-getTextWidth(s, scale))/2, y, scale, c)
+}
+
+// The tries badge is coloured like a score, a game found on the first try
+// is worth 100 and a lost one 0.
+func getCardNote(s share) float32 {
+	if !s.Won || s.MaxTries == 0 {
+		return 0
+	}
+	return float32(100 * (s.MaxTries - s.Tries + 1) / s.MaxTries)
+}
+
+func buildShareCard(s share) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, cardWidth, cardHeight))
+	fillRect(img, 0, 0, cardWidth, cardHeight, parseHexColor("#363636"))
+	light := parseHexColor("#DCDCDC")
+	white := parseHexColor("#FFFFFF")
+
+	title := fmt.Sprintf("%s #%d", strings.TrimPrefix(shareTitles[s.Mode], "#"), s.Puzzle)
+	scale := 8
+	for getTextWidth(title, scale) > cardWidth-80 {
+		scale--
+	}
+	drawCenteredText(img, title, 40+(8-scale)*7/2, scale, light)
+
+	tries := "X"
+	if s.Won {
+		tries = strconv.Itoa(s.Tries)
+	}
+	badge := fmt.Sprintf("%s/%d", tries, s.MaxTries)
+	badgeWidth := getTextWidth(badge, 6) + 60
+	fillRect(img, (cardWidth-badgeWidth)/2, 120, badgeWidth, 72, parseHexColor(getColorOfNote(getCardNote(s))))
+	drawCenteredText(img, badge, 135, 6, white)
+
+	top := 220
+	if s.Total > 0 {
+		drawCenteredText(img, fmt.Sprintf("%d/%d revealed", s.Revealed, s.Total), top, 4, light)
+		top += 50
+	} else if s.Points > 0 {
+		drawCenteredText(img, fmt.Sprintf("%d points", s.Points), top, 4, light)
+		top += 50
+	}
+
+	columns := 0
+	for _, row := range s.Grid {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+	if columns > 0 {
+		drawGrid(img, s.Grid, columns, top)
+	}
+	var ret bytes.Buffer
+	png.Encode(&ret, img)
+	return ret.Bytes()
+}
+
+// drawGrid fits the grid between top and the bottom of the card, squares are
+// never bigger than on the website.
+func drawGrid(img *image.RGBA, grid [][]color, columns, top int) {
+	size := (cardHeight - 30 - top) / len(grid)
+	if w := (cardWidth - 100) / columns; w < size {
+		size = w
+	}
+	if size > 64 {
+		size = 64
+	}
+	gap := size / 8
+	x := (cardWidth - columns*size) / 2
+	for r, row := range grid {
+		for i, c := range row {
+			fillRect(img, x+i*size+gap/2, top+r*size+gap/2, size-gap, size-gap, parseHexColor(cardColors[c]))
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+)
+
+// Guesses within this many subscriptions of the hidden club are YELLOW.
+var subscriptionsTolerance = 2000
+
+func buildClubHistory(target clubinfos, history []string, clubs []clubinfos) []byte {
+	var ret bytes.Buffer
+	ret.WriteString(`<div class="history"><div class="titles"><div>Club</div><div>League</div><div>Country</div><div>Followers</div></div>`)
+	for i := len(history) - 1; i >= 0; i-- {
+		guess, ok := getClub(clubs, history[i])
+		if !ok {
+			continue
+		}
+		ret.WriteString(`<div class="guess">`)
+		ret.WriteString(`<div><img src="` + guess.PictureUrl + `" title="` + guess.Name + `"/></div>`)
+		for _, c := range compareClubs(target, guess) {
+			ret.Write(c.html())
+		}
+		ret.WriteString(`</div>`)
+	}
+	ret.WriteString(`</div>`)
+	return ret.Bytes()
+}
+
+func compareClubs(target, guess clubinfos) []cell {
+	var ret []cell
+	if guess.League == target.League {
+		ret = append(ret, cell{YELLOW, guess.League, NONE})
+	} else {
+		ret = append(ret, cell{RED, guess.League, NONE})
+	}
+	if guess.Country == target.Country {
+		ret = append(ret, cell{YELLOW, guess.Country, NONE})
+	} else {
+		ret = append(ret, cell{RED, guess.Country, NONE})
+	}
+	return append(ret, compareNumbers(target.Subscriptions, guess.Subscriptions, subscriptionsTolerance))
+}
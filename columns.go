@@ -0,0 +1,174 @@
+package main
+
+import (
+	"strconv"
+	"time"
+)
+
+// Numeric columns only set value and tolerance: a guess within tolerance of
+// the answer is YELLOW, anything further is RED, both with an arrow.
+type column struct {
+	title     string
+	value     func(p playerinf) int
+	tolerance int
+	compare   func(p1, p2 playerinf) cell
+}
+
+type cell struct {
+	color   color
+	content string
+	arrow   arrow
+}
+
+func (c cell) html() []byte {
+	return buildTextDiv(c.color, c.content, c.arrow)
+}
+
+func (col column) render(p1, p2 playerinf) cell {
+	if col.value != nil {
+		return compareNumbers(col.value(p1), col.value(p2), col.tolerance)
+	}
+	return col.compare(p1, p2)
+}
+
+var columns = map[string]column{
+	"age": {title: "Age", tolerance: 2, value: func(p playerinf) int { return p.Age }},
+	"club": {title: "Club", compare: func(p1, p2 playerinf) cell {
+		if p1.Club == p2.Club {
+			return cell{GREEN, `<img src="` + p2.Club + `"/>`, NONE}
+		} else if p1.ClubLeague == p2.ClubLeague {
+			return cell{YELLOW, `<img src="` + p2.Club + `"/>`, NONE}
+		}
+		return cell{RED, `<img src="` + p2.Club + `"/>`, NONE}
+	}},
+	"country": {title: "Country", compare: func(p1, p2 playerinf) cell {
+		if p1.NationalTeam == p2.NationalTeam {
+			return cell{GREEN, `<img src="` + p2.NationalTeam + `"/>`, NONE}
+		} else if sameConfederation(p1.NationalTeamCode, p2.NationalTeamCode) {
+			return cell{YELLOW, `<img src="` + p2.NationalTeam + `"/>`, NONE}
+		}
+		return cell{RED, `<img src="` + p2.NationalTeam + `"/>`, NONE}
+	}},
+	"shirt": {title: "Shirt Number", tolerance: 2, value: func(p playerinf) int { return p.ShirtNumber }},
+	"position": {title: "Position", compare: func(p1, p2 playerinf) cell {
+		content := p2.DetailedPosition
+		if content == "" {
+			content = p2.Position
+		}
+		if p1.Position != p2.Position || p2.Position == "?" {
+			return cell{RED, content, NONE}
+		} else if p1.DetailedPosition == p2.DetailedPosition {
+			return cell{GREEN, content, NONE}
+		}
+		return cell{YELLOW, content, NONE}
+	}},
+	"l5":  {title: "L5", tolerance: 5, value: func(p playerinf) int { return p.L5 }},
+	"l15": {title: "L15", tolerance: 5, value: func(p playerinf) int { return p.L15 }},
+	"foot": {title: "Foot", compare: func(p1, p2 playerinf) cell {
+		if p1.Foot == p2.Foot {
+			return cell{GREEN, getShortFoot(p2.Foot), NONE}
+		}
+		return cell{RED, getShortFoot(p2.Foot), NONE}
+	}},
+	"height":      {title: "Height", tolerance: 5, value: func(p playerinf) int { return p.Height }},
+	"appearances": {title: "Apps", tolerance: 3, value: func(p playerinf) int { return p.Appearances }},
+	"supply":      {title: "Limited Supply", tolerance: 200, value: func(p playerinf) int { return p.Supply }},
+	"price": {title: "Price", compare: func(p1, p2 playerinf) cell {
+		if p1.PriceBand < 0 || p2.PriceBand < 0 {
+			return cell{RED, getPriceBandLabel(p2.PriceBand), NONE}
+		}
+		a := NONE
+		if p1.PriceBand > p2.PriceBand {
+			a = OVER
+		} else if p1.PriceBand < p2.PriceBand {
+			a = UNDER
+		}
+		if p1.PriceBand == p2.PriceBand {
+			return cell{GREEN, getPriceBandLabel(p2.PriceBand), a}
+		} else if p1.PriceBand-p2.PriceBand == 1 || p2.PriceBand-p1.PriceBand == 1 {
+			return cell{YELLOW, getPriceBandLabel(p2.PriceBand), a}
+		}
+		return cell{RED, getPriceBandLabel(p2.PriceBand), a}
+	}},
+}
+
+var classicModes = map[string][]string{
+	"classic": {"age", "club", "country", "shirt", "position", "l5", "l15"},
+	"hard":    {"club", "country", "position", "foot", "height", "appearances", "supply", "price"},
+}
+
+func getColumns(mode string) ([]column, bool) {
+	keys, ok := classicModes[mode]
+	if !ok {
+		return nil, false
+	}
+	var ret []column
+	for _, k := range keys {
+		ret = append(ret, columns[k])
+	}
+	return ret, true
+}
+
+func compareNumbers(n1, n2, tolerance int) cell {
+	content := strconv.Itoa(n2)
+	if n1 == n2 {
+		return cell{GREEN, content, NONE}
+	}
+	a := UNDER
+	if n1 > n2 {
+		a = OVER
+	}
+	if n1-n2 <= tolerance && n2-n1 <= tolerance {
+		return cell{YELLOW, content, a}
+	}
+	return cell{RED, content, a}
+}
+
+func getShortFoot(foot string) string {
+	switch foot {
+	case "LEFT":
+		return "L"
+	case "RIGHT":
+		return "R"
+	}
+	return "?"
+}
+
+// Lowest limited listing, in euro cents. A player without any listing gets -1.
+var priceBands = []struct {
+	max   int
+	label string
+}{
+	{500, "<5€"},
+	{2000, "5-20€"},
+	{5000, "20-50€"},
+	{15000, "50-150€"},
+}
+
+func getPriceBand(cents int) int {
+	if cents <= 0 {
+		return -1
+	}
+	for i, b := range priceBands {
+		if cents < b.max {
+			return i
+		}
+	}
+	return len(priceBands)
+}
+
+func getPriceBandLabel(band int) string {
+	if band < 0 {
+		return "?"
+	} else if band >= len(priceBands) {
+		return ">150€"
+	}
+	return priceBands[band].label
+}
+
+func getSeasonStartYear(t time.Time) int {
+	if t.Month() >= time.July {
+		return t.Year()
+	}
+	return t.Year() - 1
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log"
+	"strings"
+)
+
+//go:embed confederations.json
+var confederationsFile []byte
+
+var confederations = loadConfederations()
+
+func loadConfederations() map[string]string {
+	var byConfederation map[string][]string
+	if err := json.Unmarshal(confederationsFile, &byConfederation); err != nil {
+		log.Fatal("Couldn't read confederations " + err.Error())
+	}
+	ret := make(map[string]string)
+	for conf, codes := range byConfederation {
+		for _, code := range codes {
+			ret[code] = conf
+		}
+	}
+	return ret
+}
+
+// Subdivisions like GB-ENG fall back to their parent country when they are
+// not listed on their own.
+func getConfederation(code string) string {
+	if conf, ok := confederations[code]; ok {
+		return conf
+	}
+	if i := strings.Index(code, "-"); i > 0 {
+		return confederations[code[:i]]
+	}
+	return ""
+}
+
+func sameConfederation(code1, code2 string) bool {
+	conf := getConfederation(code1)
+	return conf != "" && conf == getConfederation(code2)
+}
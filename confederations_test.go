@@ -0,0 +1,83 @@
+package main
+
+import "testing"
+
+// previousCodes are the countries the old continent table knew about, none
+// of them may lose its confederation.
+var previousCodes = []string{
+	"AF", "AL", "DZ", "AS", "AD", "AO", "AI", "AG", "AR", "AM", "AW", "AU", "AT", "AZ", "BS", "BH",
+	"BD", "BB", "BY", "BE", "BZ", "BJ", "BM", "BT", "BO", "BA", "BW", "BR", "VG", "BN", "BG", "BF",
+	"BI", "KH", "CM", "CA", "CV", "KY", "CF", "TD", "CL", "CN", "CX", "CC", "CO", "KM", "CG", "CK",
+	"CR", "CI", "HR", "CU", "CY", "CZ", "DK", "DJ", "DM", "DO", "EC", "EG", "SV", "GQ", "ER", "EE",
+	"ET", "FK", "FO", "FJ", "FI", "FR", "GF", "PF", "GA", "GM", "GE", "DE", "GH", "GI", "GR", "GL",
+	"GD", "GP", "GU", "GT", "GN", "GW", "GY", "HT", "VA", "HN", "HU", "IS", "IN", "ID", "IR", "IQ",
+	"IE", "IL", "IT", "JM", "JP", "JO", "KZ", "KE", "KI", "KP", "KR", "KW", "KG", "LA", "LV", "LB",
+	"LS", "LR", "LY", "LI", "LT", "LU", "MK", "MG", "MW", "MY", "MV", "ML", "MT", "MH", "MQ", "MR",
+	"MU", "YT", "MX", "FM", "MD", "MC", "MN", "MS", "MA", "MZ", "NA", "NR", "NP", "NL", "AN", "NC",
+	"NZ", "NI", "NE", "NG", "NU", "NF", "MP", "NO", "OM", "PK", "PW", "PA", "PG", "PY", "PE", "PH",
+	"PN", "PL", "PT", "PR", "QA", "RE", "RO", "RU", "RW", "KN", "LC", "PM", "VC", "SM", "ST", "SA",
+	"SN", "SC", "SL", "SG", "SK", "SI", "SB", "SO", "ZA", "ES", "LK", "SD", "SR", "SJ", "SZ", "SE",
+	"CH", "SY", "TW", "TJ", "TZ", "TH", "TG", "TK", "TO", "TT", "TN", "TR", "TM", "TC", "TV", "UG",
+	"UA", "AE", "GB", "GB-ENG", "GB-WLS", "GB-SCT", "GB-NIR", "US", "UY", "UZ", "VU", "VE", "VN", "VI", "WF", "EH",
+	"WS", "YE", "ZR", "ZM", "ZW",
+}
+
+func TestEveryPreviousCodeHasAConfederation(t *testing.T) {
+	for _, code := range previousCodes {
+		if getConfederation(code) == "" {
+			t.Errorf("%s has no confederation", code)
+		}
+	}
+}
+
+func TestGetConfederation(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"FR", "UEFA"},
+		{"GB", "UEFA"},
+		{"GB-ENG", "UEFA"},
+		{"GB-WLS", "UEFA"},
+		{"GB-SCT", "UEFA"},
+		{"GB-NIR", "UEFA"},
+		{"BR", "CONMEBOL"},
+		{"US", "CONCACAF"},
+		{"AU", "AFC"},
+		{"SN", "CAF"},
+		{"NZ", "OFC"},
+		{"MC", "UEFA"},
+		{"RE", "CAF"},
+		{"--", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := getConfederation(tt.code); got != tt.want {
+			t.Errorf("getConfederation(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+// Subdivisions that aren't listed take the confederation of their country.
+func TestGetConfederationFallsBackToTheCountry(t *testing.T) {
+	delete(confederations, "GB-ENG")
+	defer func() { confederations["GB-ENG"] = "UEFA" }()
+	if got := getConfederation("GB-ENG"); got != "UEFA" {
+		t.Errorf("getConfederation(\"GB-ENG\") = %q, want the one of GB", got)
+	}
+	if got := getConfederation("ES-CT"); got != "UEFA" {
+		t.Errorf("getConfederation(\"ES-CT\") = %q, want the one of ES", got)
+	}
+}
+
+func TestSameConfederation(t *testing.T) {
+	if !sameConfederation("GB-ENG", "FR") {
+		t.Error("England and France should share UEFA")
+	}
+	if sameConfederation("FR", "BR") {
+		t.Error("France and Brazil shouldn't share a confederation")
+	}
+	if sameConfederation("--", "--") {
+		t.Error("unknown countries shouldn't match")
+	}
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// config is read from sordle.toml or sordle.yaml (or the file in
+// SORDLE_CONFIG) when there is one, then from the environment.
+type config struct {
+	Server struct {
+		Port      int    `toml:"port" yaml:"port"`
+		Templates string `toml:"templates" yaml:"templates"`
+		Assets    string `toml:"assets" yaml:"assets"`
+		// Timeouts are in seconds, 0 means none. Race boards are streamed so
+		// writes aren't limited by default.
+		ReadTimeout     int `toml:"read_timeout" yaml:"read_timeout"`
+		WriteTimeout    int `toml:"write_timeout" yaml:"write_timeout"`
+		IdleTimeout     int `toml:"idle_timeout" yaml:"idle_timeout"`
+		ShutdownTimeout int `toml:"shutdown_timeout" yaml:"shutdown_timeout"`
+	} `toml:"server" yaml:"server"`
+	Sorare struct {
+		Url    string `toml:"url" yaml:"url"`
+		ApiKey string `toml:"api_key" yaml:"api_key"`
+	} `toml:"sorare" yaml:"sorare"`
+	Data struct {
+		Dir string `toml:"dir" yaml:"dir"`
+	} `toml:"data" yaml:"data"`
+	Schedule struct {
+		Timezone     string `toml:"timezone" yaml:"timezone"`
+		RolloverHour int    `toml:"rollover_hour" yaml:"rollover_hour"`
+		PerUserDay   bool   `toml:"per_user_day" yaml:"per_user_day"`
+		// Launches are the days every mode was numbered from, as 2006-01-02.
+		Launches map[string]string `toml:"launches" yaml:"launches"`
+	} `toml:"schedule" yaml:"schedule"`
+	Selection struct {
+		FixtureOffset    int      `toml:"fixture_offset" yaml:"fixture_offset"`
+		Coverage         string   `toml:"coverage" yaml:"coverage"`
+		MinSubscriptions int      `toml:"min_subscriptions" yaml:"min_subscriptions"`
+		Leagues          []string `toml:"leagues" yaml:"leagues"`
+		ExcludeDays      int      `toml:"exclude_days" yaml:"exclude_days"`
+	} `toml:"selection" yaml:"selection"`
+	// Tolerances are per numeric column, comp hints are the misses before each
+	// composition hint unlocks.
+	Game struct {
+		MaxGuesses map[string]int `toml:"max_guesses" yaml:"max_guesses"`
+		HintsAfter int            `toml:"hints_after" yaml:"hints_after"`
+		Tolerances map[string]int `toml:"tolerances" yaml:"tolerances"`
+		CompHints  map[string]int `toml:"comp_hints" yaml:"comp_hints"`
+	} `toml:"game" yaml:"game"`
+	// Refreshes are in days, a pool size of 0 keeps the current size.
+	Refresh struct {
+		ClubsEvery   int `toml:"clubs_every" yaml:"clubs_every"`
+		PlayersEvery int `toml:"players_every" yaml:"players_every"`
+		PoolSize     int `toml:"pool_size" yaml:"pool_size"`
+	} `toml:"refresh" yaml:"refresh"`
+	// The admin page is off without a password.
+	Admin struct {
+		Password string `toml:"password" yaml:"password"`
+	} `toml:"admin" yaml:"admin"`
+}
+
+var settings = getDefaultConfig()
+
+var configFiles = []string{"sordle.toml", "sordle.yaml", "sordle.yml"}
+
+func getDefaultConfig() config {
+	var ret config
+	ret.Server.Port = 8080
+	ret.Server.Templates = "./*.html"
+	ret.Server.Assets = "./assets/"
+	ret.Server.ReadTimeout = 10
+	ret.Server.IdleTimeout = 60
+	ret.Server.ShutdownTimeout = 15
+	ret.Sorare.Url = "https://api.sorare.com/graphql"
+	ret.Data.Dir = "data"
+	ret.Schedule.Timezone = "Europe/Paris"
+	ret.Schedule.Launches = make(map[string]string)
+	for mode, day := range puzzleLaunches {
+		ret.Schedule.Launches[mode] = day
+	}
+	ret.Selection.FixtureOffset = gamePolicy.fixtureOffset
+	ret.Selection.Coverage = gamePolicy.coverage
+	ret.Selection.MinSubscriptions = gamePolicy.minSubscriptions
+	ret.Selection.ExcludeDays = gamePolicy.excludeDays
+	ret.Game.MaxGuesses = make(map[string]int)
+	for mode, n := range maxGuesses {
+		ret.Game.MaxGuesses[mode] = n
+	}
+	ret.Game.HintsAfter = hintsAfter
+	ret.Game.Tolerances = make(map[string]int)
+	for name, col := range columns {
+		if col.value != nil {
+			ret.Game.Tolerances[name] = col.tolerance
+		}
+	}
+	ret.Game.CompHints = make(map[string]int)
+	for _, h := range compHints {
+		ret.Game.CompHints[getCompHintKey(h.title)] = h.after
+	}
+	ret.Refresh.ClubsEvery = 7
+	ret.Refresh.PlayersEvery = 30
+	return ret
+}
+
+func loadConfig() error {
+	cfg := getDefaultConfig()
+	path := os.Getenv("SORDLE_CONFIG")
+	if path == "" {
+		for _, f := range configFiles {
+			if _, err := os.Stat(f); err == nil {
+				path = f
+				break
+			}
+		}
+	}
+	if path != "" {
+		if err := readConfigFile(path, &cfg); err != nil {
+			return err
+		}
+	}
+	if err := readConfigEnv(&cfg); err != nil {
+		return err
+	}
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+	settings = cfg
+	gamePolicy = selectionPolicy{
+		fixtureOffset:    cfg.Selection.FixtureOffset,
+		coverage:         cfg.Selection.Coverage,
+		minSubscriptions: cfg.Selection.MinSubscriptions,
+		leagues:          cfg.Selection.Leagues,
+		excludeDays:      cfg.Selection.ExcludeDays,
+	}
+	maxGuesses = cfg.Game.MaxGuesses
+	puzzleLaunches = cfg.Schedule.Launches
+	hintsAfter = cfg.Game.HintsAfter
+	for name, n := range cfg.Game.Tolerances {
+		col := columns[name]
+		col.tolerance = n
+		columns[name] = col
+	}
+	for i, h := range compHints {
+		compHints[i].after = cfg.Game.CompHints[getCompHintKey(h.title)]
+	}
+	loc, _ = time.LoadLocation(cfg.Schedule.Timezone)
+	return nil
+}
+
+func readConfigFile(path string, cfg *config) error {
+	f, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	switch filepath.Ext(path) {
+	case ".toml":
+		err = toml.Unmarshal(f, cfg)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(f, cfg)
+	default:
+		return errors.New("unknown config format " + path)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+func readConfigEnv(cfg *config) error {
+	ints := map[string]*int{
+		"PORT":                    &cfg.Server.Port,
+		"SORDLE_ROLLOVER_HOUR":    &cfg.Schedule.RolloverHour,
+		"SORDLE_READ_TIMEOUT":     &cfg.Server.ReadTimeout,
+		"SORDLE_WRITE_TIMEOUT":    &cfg.Server.WriteTimeout,
+		"SORDLE_IDLE_TIMEOUT":     &cfg.Server.IdleTimeout,
+		"SORDLE_SHUTDOWN_TIMEOUT": &cfg.Server.ShutdownTimeout,
+		"SORDLE_CLUBS_EVERY":      &cfg.Refresh.ClubsEvery,
+		"SORDLE_PLAYERS_EVERY":    &cfg.Refresh.PlayersEvery,
+		"SORDLE_POOL_SIZE":        &cfg.Refresh.PoolSize,
+		"SORDLE_HINTS_AFTER":      &cfg.Game.HintsAfter,
+	}
+	for name, v := range ints {
+		if s := os.Getenv(name); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				return fmt.Errorf("%s: %q is not a number", name, s)
+			}
+			*v = n
+		}
+	}
+	strs := map[string]*string{
+		"SORARE_API_KEY":        &cfg.Sorare.ApiKey,
+		"SORARE_URL":            &cfg.Sorare.Url,
+		"SORDLE_DATA_DIR":       &cfg.Data.Dir,
+		"SORDLE_TEMPLATES":      &cfg.Server.Templates,
+		"SORDLE_ASSETS":         &cfg.Server.Assets,
+		"SORDLE_TIMEZONE":       &cfg.Schedule.Timezone,
+		"SORDLE_ADMIN_PASSWORD": &cfg.Admin.Password,
+	}
+	for name, v := range strs {
+		if s := os.Getenv(name); s != "" {
+			*v = s
+		}
+	}
+	if s := os.Getenv("SORDLE_PER_USER_DAY"); s != "" {
+		cfg.Schedule.PerUserDay = s == "true" || s == "1"
+	}
+	if s := os.Getenv("SORDLE_LEAGUES"); s != "" {
+		cfg.Selection.Leagues = nil
+		for _, league := range strings.Split(s, ",") {
+			if league = strings.TrimSpace(league); league != "" {
+				cfg.Selection.Leagues = append(cfg.Selection.Leagues, league)
+			}
+		}
+	}
+	return nil
+}
+
+func (cfg config) validate() error {
+	var errs []string
+	if cfg.Server.Port <= 0 || cfg.Server.Port > 65535 {
+		errs = append(errs, fmt.Sprintf("server.port %d is not a valid port", cfg.Server.Port))
+	}
+	if u, err := url.Parse(cfg.Sorare.Url); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		errs = append(errs, fmt.Sprintf("sorare.url %q is not a valid url", cfg.Sorare.Url))
+	}
+	if info, err := os.Stat(cfg.Data.Dir); err != nil || !info.IsDir() {
+		errs = append(errs, fmt.Sprintf("data.dir %q is not a directory", cfg.Data.Dir))
+	}
+	if cfg.Server.ReadTimeout < 0 || cfg.Server.WriteTimeout < 0 || cfg.Server.IdleTimeout < 0 || cfg.Server.ShutdownTimeout < 0 {
+		errs = append(errs, "server timeouts can't be negative")
+	}
+	if _, err := time.LoadLocation(cfg.Schedule.Timezone); err != nil {
+		errs = append(errs, fmt.Sprintf("schedule.timezone %q is unknown", cfg.Schedule.Timezone))
+	}
+	if cfg.Schedule.RolloverHour < 0 || cfg.Schedule.RolloverHour > 23 {
+		errs = append(errs, fmt.Sprintf("schedule.rollover_hour %d is not between 0 and 23", cfg.Schedule.RolloverHour))
+	}
+	defaults := getDefaultConfig()
+	for mode, day := range cfg.Schedule.Launches {
+		if _, ok := defaults.Schedule.Launches[mode]; !ok {
+			errs = append(errs, fmt.Sprintf("schedule.launches has an unknown mode %q", mode))
+		} else if _, err := time.Parse("2006-01-02", day); err != nil {
+			errs = append(errs, fmt.Sprintf("schedule.launches.%s %q is not a date", mode, day))
+		}
+	}
+	if strings.TrimSpace(cfg.Selection.Coverage) == "" {
+		errs = append(errs, "selection.coverage can't be empty, FULL takes the games with every score")
+	}
+	if cfg.Selection.FixtureOffset < 0 {
+		errs = append(errs, "selection.fixture_offset can't be negative")
+	}
+	if cfg.Selection.MinSubscriptions < 0 {
+		errs = append(errs, "selection.min_subscriptions can't be negative")
+	}
+	if cfg.Selection.ExcludeDays < 0 {
+		errs = append(errs, "selection.exclude_days can't be negative")
+	}
+	for mode, n := range cfg.Game.MaxGuesses {
+		if _, ok := defaults.Game.MaxGuesses[mode]; !ok {
+			errs = append(errs, fmt.Sprintf("game.max_guesses has an unknown mode %q", mode))
+		} else if n <= 0 {
+			errs = append(errs, fmt.Sprintf("game.max_guesses.%s must be at least 1", mode))
+		}
+	}
+	if cfg.Game.HintsAfter < 0 {
+		errs = append(errs, "game.hints_after can't be negative")
+	}
+	for name, n := range cfg.Game.Tolerances {
+		if _, ok := defaults.Game.Tolerances[name]; !ok {
+			errs = append(errs, fmt.Sprintf("game.tolerances has an unknown numeric column %q", name))
+		} else if n < 0 {
+			errs = append(errs, fmt.Sprintf("game.tolerances.%s can't be negative", name))
+		}
+	}
+	for name, n := range cfg.Game.CompHints {
+		if _, ok := defaults.Game.CompHints[name]; !ok {
+			errs = append(errs, fmt.Sprintf("game.comp_hints has an unknown hint %q", name))
+		} else if n < 0 {
+			errs = append(errs, fmt.Sprintf("game.comp_hints.%s can't be negative", name))
+		}
+	}
+	if cfg.Refresh.ClubsEvery <= 0 || cfg.Refresh.PlayersEvery <= 0 {
+		errs = append(errs, "refresh intervals must be at least a day")
+	}
+	if cfg.Refresh.PoolSize < 0 {
+		errs = append(errs, "refresh.pool_size can't be negative")
+	}
+	if len(errs) > 0 {
+		return errors.New("invalid config: " + strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// printConfig shows the effective config, the api key is only hinted at.
+func printConfig() {
+	cfg := settings
+	if len(cfg.Sorare.ApiKey) > 8 {
+		cfg.Sorare.ApiKey = cfg.Sorare.ApiKey[:4] + strings.Repeat("*", len(cfg.Sorare.ApiKey)-4)
+	} else if cfg.Sorare.ApiKey != "" {
+		cfg.Sorare.ApiKey = "********"
+	}
+	if cfg.Admin.Password != "" {
+		cfg.Admin.Password = "********"
+	}
+	out, _ := toml.Marshal(cfg)
+	fmt.Print(string(out))
+}
+
+func seconds(n int) time.Duration {
+	return time.Duration(n) * time.Second
+}
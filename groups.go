@@ -0,0 +1,209 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// The finished games of group members and accounts are kept, groups only
+// pick the results of their members.
+type result struct {
+	Token   string
+	Mode    string
+	Puzzle  string
+	Day     time.Time
+	At      time.Time
+	Tries   int
+	Seconds int
+	Won     bool
+}
+
+type group struct {
+	Name    string
+	Code    string
+	Members map[string]string
+}
+
+type standing struct {
+	Nickname string
+	Solved   int
+	Tries    float64
+	Seconds  int
+}
+
+var results []result
+var resultsChanged bool
+var groups = make(map[string]*group)
+var groupsMu sync.Mutex
+
+// saveMu keeps two saves of the results or of the shares from writing out of
+// order.
+var saveMu sync.Mutex
+
+func loadGroups() {
+	if r, err := pick[[]result]("results"); err == nil {
+		results = r
+		// The first save prunes what older versions kept for everyone.
+		resultsChanged = true
+	}
+	if g, err := pick[map[string]*group]("groups"); err == nil {
+		groups = g
+	}
+}
+
+// recordResult keeps the day of the puzzle along with when it was played, a
+// game finished after midnight still counts for its own day.
+func recordResult(token, mode string, day time.Time, g game) {
+	_, hasAccount := getAccount(token)
+	groupsMu.Lock()
+	defer groupsMu.Unlock()
+	if !hasAccount && !isMember(token) {
+		return
+	}
+	results = append(results, result{
+		Token:   token,
+		Mode:    mode,
+		Puzzle:  g.puzzle,
+		Day:     day,
+		At:      time.Now(),
+		Tries:   g.guesses,
+		Seconds: int(time.Since(g.started).Seconds()),
+		Won:     g.won,
+	})
+	resultsChanged = true
+}
+
+// isMember must be called with groupsMu held.
+func isMember(token string) bool {
+	for _, g := range groups {
+		if _, ok := g.Members[token]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// saveResults writes the results once a minute when they changed, so the end
+// of a game doesn't wait on the disk.
+func saveResults() {
+	for range time.Tick(time.Minute) {
+		flushResults()
+	}
+}
+
+// flushResults drops the results of sessions that left every group and have
+// no account, then writes them.
+func flushResults() {
+	saveMu.Lock()
+	defer saveMu.Unlock()
+	owners := getAccountTokens()
+	groupsMu.Lock()
+	if !resultsChanged {
+		groupsMu.Unlock()
+		return
+	}
+	var kept []result
+	for _, r := range results {
+		if owners[r.Token] || isMember(r.Token) {
+			kept = append(kept, r)
+		}
+	}
+	results = kept
+	resultsChanged = false
+	snapshot := append([]result(nil), results...)
+	groupsMu.Unlock()
+	dump("results", snapshot)
+}
+
+func createGroup(name, token, nickname string) *group {
+	groupsMu.Lock()
+	defer groupsMu.Unlock()
+	code := newRoomCode()
+	for groups[code] != nil {
+		code = newRoomCode()
+	}
+	g := &group{Name: name, Code: code, Members: map[string]string{token: nickname}}
+	groups[code] = g
+	dump("groups", groups)
+	return g
+}
+
+func joinGroup(code, token, nickname string) (*group, bool) {
+	groupsMu.Lock()
+	defer groupsMu.Unlock()
+	g, ok := groups[code]
+	if !ok {
+		return nil, false
+	}
+	g.Members[token] = nickname
+	dump("groups", groups)
+	return g, true
+}
+
+func getGroup(code, token string) (group, bool) {
+	groupsMu.Lock()
+	defer groupsMu.Unlock()
+	g, ok := groups[code]
+	if !ok {
+		return group{}, false
+	}
+	if _, member := g.Members[token]; !member {
+		return group{}, false
+	}
+	return *g, true
+}
+
+func getGroupsOf(token string) []group {
+	groupsMu.Lock()
+	defer groupsMu.Unlock()
+	var ret []group
+	for _, g := range groups {
+		if _, ok := g.Members[token]; ok {
+			ret = append(ret, *g)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret
+}
+
+// getStandings ranks the members on the games they solved since the given
+// time, then on their average number of tries.
+func getStandings(g group, since time.Time) []standing {
+	groupsMu.Lock()
+	defer groupsMu.Unlock()
+	byToken := make(map[string]*standing)
+	tries := make(map[string]int)
+	for token, nickname := range g.Members {
+		byToken[token] = &standing{Nickname: nickname}
+	}
+	for _, r := range results {
+		s, ok := byToken[r.Token]
+		if !ok || !r.Won || r.At.Before(since) {
+			continue
+		}
+		s.Solved++
+		s.Seconds += r.Seconds
+		tries[r.Token] += r.Tries
+	}
+	var ret []standing
+	for token, s := range byToken {
+		if s.Solved > 0 {
+			s.Tries = float64(tries[token]) / float64(s.Solved)
+			s.Seconds /= s.Solved
+		}
+		ret = append(ret, *s)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Solved != ret[j].Solved {
+			return ret[i].Solved > ret[j].Solved
+		}
+		return ret[i].Tries < ret[j].Tries
+	})
+	return ret
+}
+
+func getStartOfWeek(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
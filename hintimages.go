@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"image"
+	imagecolor "image/color"
+	_ "image/jpeg"
+	"image/png"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// The club and silhouette hints are drawn here and sent inline, so the page
+// never holds the url of the badge or of the player picture.
+const hintImageSize = 64
+
+var hintImages = make(map[string]string)
+var hintImagesMu sync.Mutex
+
+var hintImageClient = &http.Client{Timeout: 10 * time.Second}
+
+func getBlurredHint(url string) string {
+	return getHintImage("blurred", url, pixelate)
+}
+
+func getSilhouetteHint(url string) string {
+	return getHintImage("silhouette", url, silhouette)
+}
+
+// getHintImage gives a data url of the transformed picture, or an empty
+// string when it can't be fetched.
+func getHintImage(kind, url string, transform func(image.Image) image.Image) string {
+	key := kind + " " + url
+	hintImagesMu.Lock()
+	if ret, ok := hintImages[key]; ok {
+		hintImagesMu.Unlock()
+		return ret
+	}
+	hintImagesMu.Unlock()
+	src, err := fetchImage(url)
+	if err != nil {
+		return ""
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, transform(src)); err != nil {
+		return ""
+	}
+	ret := "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+	hintImagesMu.Lock()
+	// Only a few puzzles are played at once, the cache starts over when it
+	// holds older ones.
+	if len(hintImages) > 32 {
+		hintImages = make(map[string]string)
+	}
+	hintImages[key] = ret
+	hintImagesMu.Unlock()
+	return ret
+}
+
+func fetchImage(url string) (image.Image, error) {
+	if url == "" {
+		return nil, errors.New("no picture")
+	}
+	res, err := hintImageClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.New(res.Status)
+	}
+	img, _, err := image.Decode(res.Body)
+	return img, err
+}
+
+// pixelate averages the picture on a 6x6 grid, only its colours are left.
+func pixelate(src image.Image) image.Image {
+	const cells = 6
+	b := src.Bounds()
+	ret := image.NewRGBA(image.Rect(0, 0, hintImageSize, hintImageSize))
+	for cy := 0; cy < cells; cy++ {
+		for cx := 0; cx < cells; cx++ {
+			var r, g, bl, a, n uint64
+			for y := b.Min.Y + cy*b.Dy()/cells; y < b.Min.Y+(cy+1)*b.Dy()/cells; y++ {
+				for x := b.Min.X + cx*b.Dx()/cells; x < b.Min.X+(cx+1)*b.Dx()/cells; x++ {
+					pr, pg, pb, pa := src.At(x, y).RGBA()
+					r, g, bl, a, n = r+uint64(pr), g+uint64(pg), bl+uint64(pb), a+uint64(pa), n+1
+				}
+			}
+			if n == 0 {
+				continue
+			}
+			c := imagecolor.RGBA64{uint16(r / n), uint16(g / n), uint16(bl / n), uint16(a / n)}
+			for y := cy * hintImageSize / cells; y < (cy+1)*hintImageSize/cells; y++ {
+				for x := cx * hintImageSize / cells; x < (cx+1)*hintImageSize/cells; x++ {
+					ret.Set(x, y, c)
+				}
+			}
+		}
+	}
+	return ret
+}
+
+// silhouette keeps the shape of the picture in black, the transparent
+// background stays transparent.
+func silhouette(src image.Image) image.Image {
+	b := src.Bounds()
+	ret := image.NewRGBA(image.Rect(0, 0, hintImageSize, hintImageSize))
+	for y := 0; y < hintImageSize; y++ {
+		for x := 0; x < hintImageSize; x++ {
+			_, _, _, a := src.At(b.Min.X+x*b.Dx()/hintImageSize, b.Min.Y+y*b.Dy()/hintImageSize).RGBA()
+			if a > 0x4000 {
+				ret.Set(x, y, imagecolor.RGBA{0, 0, 0, 255})
+			}
+		}
+	}
+	return ret
+}
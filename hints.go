@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Number of wrong guesses before the first hint, each following hint
+// unlocks one miss later. It can be changed in the game section of the config.
+var hintsAfter = 3
+
+var hints = []struct {
+	title  string
+	render func(p playerinf) string
+}{
+	{"Initial", func(p playerinf) string {
+		r := []rune(p.Name)
+		if len(r) == 0 {
+			return "?"
+		}
+		return strings.ToUpper(string(r[0]))
+	}},
+	{"Club", func(p playerinf) string {
+		if src := getBlurredHint(p.Club); src != "" {
+			return `<img src="` + src + `"/>`
+		}
+		return "?"
+	}},
+	{"Silhouette", func(p playerinf) string {
+		if src := getSilhouetteHint(p.PicUrl); src != "" {
+			return `<img src="` + src + `"/>`
+		}
+		return "?"
+	}},
+	{"Country", func(p playerinf) string {
+		return `<img src="` + p.NationalTeam + `"/>`
+	}},
+}
+
+func buildHints(p playerinf, mode string, g game) bytes.Buffer {
+	var ret bytes.Buffer
+	for i := 0; i < g.hints && i < len(hints); i++ {
+		ret.WriteString(fmt.Sprintf(`<div class="hint"><span>%s</span>%s</div>`, hints[i].title, hints[i].render(p)))
+	}
+	if g.won || g.lost || g.hints >= len(hints) {
+		return ret
+	}
+	if left := hintsAfter + g.hints - g.guesses; left > 0 {
+		ret.WriteString(fmt.Sprintf(`<p>Next hint after %d more misses</p>`, left))
+	} else {
+		ret.WriteString(fmt.Sprintf(`<button hx-post="/hints?mode=%s" hx-target="#hints">Get a hint</button>`, mode))
+	}
+	return ret
+}
+
+func getHintsPanel(slug, mode string, g game) bytes.Buffer {
+	var p playerinf
+	if g.hints > 0 {
+		ch := make(chan playerinf, 1)
+		getPlayerInformations(slug, ch)
+		p = <-ch
+	}
+	return buildHints(p, mode, g)
+}
+
+func getHintsText(n int) string {
+	if n == 0 {
+		return ""
+	} else if n == 1 {
+		return " with 1 hint"
+	}
+	return fmt.Sprintf(" with %d hints", n)
+}
+
+// Composition hints are unlocked in order, each one once the player has made
+// at least after wrong guesses. Hints without render are drawn on the pitch.
+var compHints = []struct {
+	title  string
+	after  int
+	render func(f formation) string
+}{
+	{"Formation", 0, nil},
+	{"Positions", 0, nil},
+	{"Home or away", 2, func(f formation) string {
+		if f.match.isHome {
+			return "Home"
+		}
+		return "Away"
+	}},
+	{"Opponent", 4, func(f formation) string {
+		return `<img src="` + f.match.opponentPictureUrl + `" title="` + f.match.opponentName + `"/>`
+	}},
+	{"Final score", 6, func(f formation) string {
+		return fmt.Sprintf("%d - %d", f.match.goalsFor, f.match.goalsAgainst)
+	}},
+	{"Competition", 8, func(f formation) string {
+		return f.match.competition
+	}},
+}
+
+func buildCompHints(g game, f formation) []byte {
+	var ret bytes.Buffer
+	ret.WriteString(`<div id="context">`)
+	for i, h := range compHints {
+		if h.render == nil || (i >= g.hints && !g.won && !g.lost) {
+			continue
+		}
+		ret.WriteString(fmt.Sprintf(`<div class="hint"><span>%s</span>%s</div>`, h.title, h.render(f)))
+	}
+	ret.WriteString(`</div>`)
+	return ret.Bytes()
+}
+
+func usedCompHint(g game, title string) bool {
+	for i := 0; i < g.hints && i < len(compHints); i++ {
+		if compHints[i].title == title {
+			return true
+		}
+	}
+	return false
+}
+
+func buildCompHintButton(mode string, g game) string {
+	if g.hints >= len(compHints) {
+		return ""
+	}
+	next := compHints[g.hints]
+	if left := next.after - g.guesses; left > 0 {
+		return fmt.Sprintf(`<p class="next-hint">Next hint after %d more misses</p>`, left)
+	}
+	return fmt.Sprintf(`<button class="hint-button" hx-post="/hints?mode=%s" hx-target="#results">Get a hint (%s)</button>`, mode, next.title)
+}
+
+// getCompHintKey names a composition hint in the config, "Home or away" is
+// home_or_away.
+func getCompHintKey(title string) string {
+	return strings.ReplaceAll(strings.ToLower(title), " ", "_")
+}
@@ -4,15 +4,21 @@ import (
 	"bytes"
 	"context"
 	"encoding/gob"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"math/rand"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -20,79 +26,722 @@ import (
 	"github.com/machinebox/graphql"
 )
 
-var numberOfFound = 0
+// The counters are shared by every handler, they are reset on the first
+// classic page of a new day.
+var numberOfFound atomic.Int64
+var numberOfHints atomic.Int64
+var numberOfLost atomic.Int64
+var numberOfScoreFound atomic.Int64
+var numberOfScorePoints atomic.Int64
+var countersDay time.Time
+var countersMu sync.Mutex
+
+func resetCounters(today time.Time) {
+	countersMu.Lock()
+	defer countersMu.Unlock()
+	if today.Equal(countersDay) {
+		return
+	}
+	countersDay = today
+	numberOfFound.Store(0)
+	numberOfHints.Store(0)
+	numberOfLost.Store(0)
+	fmt.Println(getClassicAnswer(today))
+	numberOfScoreFound.Store(0)
+	numberOfScorePoints.Store(0)
+}
 
 func main() {
-	p, _ := pick[[]playersub]("players")
-	loc, _ := time.LoadLocation("Europe/Paris")
-	randomDate := time.Date(2023, time.May, 0, 0, 0, 0, 0, loc)
-	index := (int(time.Now().In(loc).Sub(randomDate).Hours()) / 24) % len(p)
-	todayGame := getRandomGameFromLastGameweek()
-	allClubs := getAllClubs()
-	fmt.Println(todayGame)
+	if err := loadConfig(); err != nil {
+		log.Fatal("Couldn't load the config " + err.Error())
+	}
+	if len(os.Args) > 1 {
+		runCommand(os.Args[1:])
+		return
+	}
+	loadPool()
+	countersDay = getToday()
+	// compGames is empty until today's games are loaded.
+	compGames := func(mode string) []formation {
+		d := getDaily()
+		if compModes[mode].gameweeks > 1 {
+			return d.season
+		}
+		if d.comp.slug == "" {
+			return nil
+		}
+		return []formation{d.comp}
+	}
+	// requireDaily holds back the pages needing Sorare until the warm-up is done.
+	requireDaily := func(c *gin.Context) {
+		if !getDaily().ready() {
+			c.Data(http.StatusServiceUnavailable, "text/html; charset=utf-8", []byte(`<div class="error" id="error">Error : Today's games are still loading, try again in a minute !</div>`))
+			c.Abort()
+		}
+	}
+	// classicPuzzle is today's player, or the one of the player's own date
+	// when the schedule follows the players.
+	classicPuzzle := func(c *gin.Context) (string, time.Time, bool) {
+		if !settings.Schedule.PerUserDay || c.Query("date") == "" {
+			return getClassicAnswer(getToday()), getToday(), true
+		}
+		day, ok := getClientDate(c)
+		if !ok {
+			return "", day, false
+		}
+		return getClassicAnswer(day), day, true
+	}
+
+	go warmUp()
+	go expireRooms()
+	go expireSessions()
+	loadGroups()
+	loadAccounts()
+	go saveResults()
+	loadShares()
+	go saveShares()
+	loadRefreshes()
 
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.Default()
 	r.Use(cors.Default())
 
-	r.Static("/assets", "./assets/")
-	r.LoadHTMLGlob("./*.html")
+	r.Static("/assets", settings.Server.Assets)
+	r.LoadHTMLGlob(settings.Server.Templates)
 	r.GET("/", func(c *gin.Context) {
 		c.HTML(http.StatusOK, "index.html", nil)
 	})
 
 	r.GET("/classic", func(c *gin.Context) {
-		newIndex := (int(time.Now().In(loc).Sub(randomDate).Hours()) / 24) % len(p)
-		if newIndex != index {
-			index = newIndex
-			numberOfFound = 0
-			fmt.Println(p[index])
-			todayGame = getRandomGameFromLastGameweek()
-		}
-		c.HTML(http.StatusOK, "classic.html", nil)
+		resetCounters(getToday())
+		mode := c.DefaultQuery("mode", "classic")
+		cols, ok := getColumns(mode)
+		if !ok {
+			c.Redirect(http.StatusFound, "/classic")
+			return
+		}
+		var titles []string
+		for _, col := range cols {
+			titles = append(titles, col.title)
+		}
+		_, day, ok := classicPuzzle(c)
+		if !ok {
+			day = getToday()
+		}
+		c.HTML(http.StatusOK, "classic.html", gin.H{"Mode": mode, "Titles": titles, "Columns": len(titles) + 1, "MaxGuesses": maxGuesses[mode], "Puzzle": getPuzzleNumber(mode, day), "PerUserDay": settings.Schedule.PerUserDay})
+	})
+	// The page itself doesn't know the date of the player, its number is
+	// asked again with it when the puzzle follows the players.
+	r.GET("/puzzle-number", func(c *gin.Context) {
+		mode := c.DefaultQuery("mode", "classic")
+		_, day, ok := classicPuzzle(c)
+		if _, known := classicModes[mode]; !known || !ok {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(fmt.Sprintf("#%d", getPuzzleNumber(mode, day))))
 	})
 	r.GET("/player", func(c *gin.Context) {
 		player := c.DefaultQuery("player", "")
-		trys, _ := strconv.Atoi(c.DefaultQuery("trys", "0"))
-		res, _ := comparePlayerInformations(p[index].Slug, player, trys)
+		mode := c.DefaultQuery("mode", "classic")
+		cols, ok := getColumns(mode)
+		if !ok {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		puzzle, day, ok := classicPuzzle(c)
+		if !ok {
+			c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(`<div class="error" id="error">Error : Your date is off, check your clock !</div>`))
+			return
+		}
+		var g game
+		withGame(c, mode, puzzle, func(cur *game) { g = *cur })
+		if g.won || g.lost {
+			c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(`<div class="error" id="error">Error : Today's game is over, come back tomorrow !</div>`))
+			return
+		}
+		res, row, winner, valid := comparePlayerInformations(puzzle, player, g.guesses+1, g.hints, cols)
+		ended, over := false, false
+		if valid {
+			// Another request may have ended the game during the call to
+			// Sorare, the guess is dropped then.
+			withGame(c, mode, puzzle, func(cur *game) {
+				if cur.won || cur.lost || cur.guesses >= maxGuesses[mode] {
+					over = true
+					return
+				}
+				cur.guesses++
+				cur.rows = append(cur.rows, row)
+				if winner {
+					cur.won = true
+					ended = true
+					numberOfFound.Add(1)
+				} else if cur.guesses >= maxGuesses[mode] {
+					cur.lost = true
+					ended = true
+					numberOfLost.Add(1)
+				}
+				g = *cur
+			})
+		}
+		if over {
+			c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(`<div class="error" id="error">Error : Today's game is over, come back tomorrow !</div>`))
+			return
+		}
+		if ended {
+			recordResult(getSessionToken(c), mode, day, g)
+		}
+		if g.lost {
+			res.Write(revealPlayer(puzzle))
+		}
+		c.Header("HX-Trigger", "guessed")
+		c.Data(http.StatusOK, "text/html; charset=utf-8", res.Bytes())
+	})
+	r.GET("/hints", func(c *gin.Context) {
+		mode := c.DefaultQuery("mode", "classic")
+		puzzle, _, ok := classicPuzzle(c)
+		if !ok {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		var g game
+		withGame(c, mode, puzzle, func(cur *game) { g = *cur })
+		res := getHintsPanel(puzzle, mode, g)
+		c.Data(http.StatusOK, "text/html; charset=utf-8", res.Bytes())
+	})
+	r.POST("/hints", func(c *gin.Context) {
+		mode := c.DefaultQuery("mode", "classic")
+		var g game
+		if _, ok := compModes[mode]; ok {
+			if requireDaily(c); c.IsAborted() {
+				return
+			}
+			games := compGames(mode)
+			withGame(c, mode, games[0].slug, func(cur *game) {
+				if !cur.won && !cur.lost && cur.hints < len(compHints) && cur.guesses >= compHints[cur.hints].after {
+					cur.hints++
+					numberOfHints.Add(1)
+				}
+				g = *cur
+			})
+			res := testClub(mode, g, games, getClubs())
+			c.Data(http.StatusOK, "text/html; charset=utf-8", res.Bytes())
+			return
+		}
+		puzzle, _, ok := classicPuzzle(c)
+		if !ok {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		withGame(c, mode, puzzle, func(cur *game) {
+			if !cur.won && !cur.lost && cur.hints < len(hints) && cur.guesses >= hintsAfter+cur.hints {
+				cur.hints++
+				numberOfHints.Add(1)
+			}
+			g = *cur
+		})
+		res := getHintsPanel(puzzle, mode, g)
 		c.Data(http.StatusOK, "text/html; charset=utf-8", res.Bytes())
 	})
 	r.GET("/all-players", func(c *gin.Context) {
 		var res bytes.Buffer
-		for _, player := range p {
+		for _, player := range getPool() {
 			res.WriteString(fmt.Sprintf(`<option value="%s">%s</option>`, player.Slug, player.DisplayName))
 		}
 		c.Data(http.StatusOK, "text/html; charset=utf-8", res.Bytes())
 	})
 	r.GET("/nb-players", func(c *gin.Context) {
 		var res bytes.Buffer
-		res.WriteString(fmt.Sprintf("<h2>Today %d people found !</h2>", numberOfFound))
+		res.WriteString(fmt.Sprintf("<h2>Today %d people found ! (%d hints used, %d lost)</h2>", numberOfFound.Load(), numberOfHints.Load(), numberOfLost.Load()))
 		c.Data(http.StatusOK, "text/html; charset=utf-8", res.Bytes())
 	})
-	r.GET("/comp", func(c *gin.Context) {
-		c.HTML(http.StatusOK, "comp.html", nil)
+	r.GET("/comp", requireDaily, func(c *gin.Context) {
+		mode := c.DefaultQuery("mode", "comp")
+		if _, ok := compModes[mode]; !ok {
+			c.Redirect(http.StatusFound, "/comp")
+			return
+		}
+		c.HTML(http.StatusOK, "comp.html", gin.H{"Mode": mode, "Bench": compModes[mode].bench, "Gameweeks": compModes[mode].gameweeks, "MaxGuesses": maxGuesses[mode], "RevealRule": getRevealStrategy(compGames(mode)[0].reveal).rule, "Puzzle": getPuzzleNumber(mode, getDaily().day)})
 	})
-	r.GET("/compare-clubs", func(c *gin.Context) {
+	r.GET("/compare-clubs", requireDaily, func(c *gin.Context) {
 		club := c.DefaultQuery("club", "")
-		trys, _ := strconv.Atoi(c.DefaultQuery("trys", "0"))
-		res, _ := testClub(club, trys-1, todayGame)
+		mode := c.DefaultQuery("mode", "comp")
+		if _, ok := compModes[mode]; !ok {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		games := compGames(mode)
+		var g game
+		withGame(c, mode, games[0].slug, func(cur *game) { g = *cur })
+		_, isClub := getClub(getClubs(), club)
+		valid := club == "" || g.won || g.lost || isClub
+		if club != "" && valid && !g.won && !g.lost {
+			ended := false
+			withGame(c, mode, games[0].slug, func(cur *game) {
+				if cur.won || cur.lost || cur.guesses >= maxGuesses[mode] {
+					g = *cur
+					return
+				}
+				cur.guesses++
+				if club == games[0].slug {
+					cur.won = true
+					ended = true
+					numberOfFound.Add(1)
+				} else {
+					cur.history = append(cur.history, club)
+					if cur.guesses >= maxGuesses[mode] {
+						cur.lost = true
+						ended = true
+						numberOfLost.Add(1)
+					}
+				}
+				g = *cur
+			})
+			if ended {
+				recordResult(getSessionToken(c), mode, getDaily().day, g)
+			}
+		}
+		res := testClub(mode, g, games, getClubs())
+		if !valid {
+			res.WriteString(`<div class="error" id="error">Error : Please pick a club in the list</div>`)
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", res.Bytes())
+	})
+	r.POST("/give-up", func(c *gin.Context) {
+		mode := c.DefaultQuery("mode", "classic")
+		_, isComp := compModes[mode]
+		puzzle, day, ok := classicPuzzle(c)
+		if isComp {
+			if requireDaily(c); c.IsAborted() {
+				return
+			}
+			puzzle = compGames(mode)[0].slug
+			day = getDaily().day
+		} else if _, isClassic := classicModes[mode]; !isClassic {
+			c.Status(http.StatusNotFound)
+			return
+		} else if !ok {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		var g game
+		ended := false
+		withGame(c, mode, puzzle, func(cur *game) {
+			if !cur.won && !cur.lost {
+				cur.lost = true
+				ended = true
+				numberOfLost.Add(1)
+			}
+			g = *cur
+		})
+		if ended {
+			recordResult(getSessionToken(c), mode, day, g)
+		}
+		if isComp {
+			res := testClub(mode, g, compGames(mode), getClubs())
+			c.Data(http.StatusOK, "text/html; charset=utf-8", res.Bytes())
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", revealPlayer(puzzle))
+	})
+	r.GET("/score", requireDaily, func(c *gin.Context) {
+		c.HTML(http.StatusOK, "score.html", gin.H{"MaxGuesses": maxGuesses["score"], "Puzzle": getPuzzleNumber("score", getDaily().day)})
+	})
+	r.GET("/score-field", requireDaily, func(c *gin.Context) {
+		scoreGame := getDaily().score
+		var g game
+		withGame(c, "score", scoreGame[0].slug+":"+scoreGame[1].slug, func(cur *game) { g = *cur })
+		res := buildScoreField(g, scoreGame)
+		c.Data(http.StatusOK, "text/html; charset=utf-8", res.Bytes())
+	})
+	r.POST("/score-guess", requireDaily, func(c *gin.Context) {
+		d := getDaily()
+		scoreGame := d.score
+		pick := c.DefaultQuery("pick", "")
+		player, ok := getScorePick(scoreGame, pick)
+		var g game
+		ended := false
+		withGame(c, "score", scoreGame[0].slug+":"+scoreGame[1].slug, func(cur *game) {
+			if ok && !cur.won && !cur.lost && !contains(cur.history, pick) {
+				cur.guesses++
+				cur.history = append(cur.history, pick)
+				if player.score == getTopScore(scoreGame) {
+					cur.won = true
+					ended = true
+					numberOfScoreFound.Add(1)
+					numberOfScorePoints.Add(int64(getScorePoints(*cur)))
+				} else if cur.guesses >= maxGuesses["score"] {
+					cur.lost = true
+					ended = true
+					numberOfLost.Add(1)
+				}
+			}
+			g = *cur
+		})
+		if ended {
+			recordResult(getSessionToken(c), "score", d.day, g)
+		}
+		res := buildScoreField(g, scoreGame)
+		c.Data(http.StatusOK, "text/html; charset=utf-8", res.Bytes())
+	})
+	r.GET("/score-stats", func(c *gin.Context) {
+		var res bytes.Buffer
+		average := 0.0
+		if found := numberOfScoreFound.Load(); found > 0 {
+			average = float64(numberOfScorePoints.Load()) / float64(found)
+		}
+		res.WriteString(fmt.Sprintf("<h2>Today %d people found the top scorer ! (%.1f points on average)</h2>", numberOfScoreFound.Load(), average))
 		c.Data(http.StatusOK, "text/html; charset=utf-8", res.Bytes())
 	})
+	r.GET("/race", func(c *gin.Context) {
+		c.HTML(http.StatusOK, "race.html", gin.H{"Code": c.DefaultQuery("code", "")})
+	})
+	r.POST("/race", func(c *gin.Context) {
+		nickname := strings.TrimSpace(c.PostForm("nickname"))
+		code := strings.ToUpper(strings.TrimSpace(c.PostForm("code")))
+		if nickname == "" {
+			c.HTML(http.StatusOK, "race.html", gin.H{"Code": code, "Error": "Please pick a nickname"})
+			return
+		}
+		var joined *room
+		if code == "" {
+			pool := getPool()
+			if len(pool) == 0 {
+				c.HTML(http.StatusOK, "race.html", gin.H{"Code": code, "Error": "The players are still loading, try again in a minute"})
+				return
+			}
+			joined = createRoom(pool[rand.Intn(len(pool))].Slug)
+		} else if existing, ok := getRoom(code); ok {
+			joined = existing
+		} else {
+			c.HTML(http.StatusOK, "race.html", gin.H{"Code": code, "Error": "This room doesn't exist or has expired"})
+			return
+		}
+		joined.join(getSessionToken(c), nickname)
+		c.Redirect(http.StatusSeeOther, "/race/"+joined.code)
+	})
+	r.GET("/race/:code", func(c *gin.Context) {
+		room, ok := getRoom(c.Param("code"))
+		if !ok {
+			c.Redirect(http.StatusFound, "/race")
+			return
+		}
+		if !room.isRacer(getSessionToken(c)) {
+			c.Redirect(http.StatusFound, "/race?code="+room.code)
+			return
+		}
+		cols, _ := getColumns("classic")
+		var titles []string
+		for _, col := range cols {
+			titles = append(titles, col.title)
+		}
+		c.HTML(http.StatusOK, "race.html", gin.H{"Room": room.code, "Titles": titles, "Columns": len(titles) + 1})
+	})
+	r.GET("/race/:code/guess", func(c *gin.Context) {
+		room, ok := getRoom(c.Param("code"))
+		if !ok || !room.isRacer(getSessionToken(c)) {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		player := c.DefaultQuery("player", "")
+		cols, _ := getColumns("classic")
+		guess, cells, ok := compareRow(room.slug, player, cols)
+		if !ok {
+			c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(`<div class="error" id="error">Error : Please pick a player in the list</div>`))
+			return
+		}
+		winner := room.slug == player
+		room.addRow(getSessionToken(c), cells, winner)
+		res := buildRow(guess, cells)
+		if winner {
+			res = append(res, []byte(fmt.Sprintf(`
+				<div class="winner" id="winner">
+					<h2>Good Job ! You found <span>%s</span> !</h2>
+				</div>
+			`, guess.Name))...)
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", res)
+	})
+	r.GET("/race/:code/events", func(c *gin.Context) {
+		room, ok := getRoom(c.Param("code"))
+		if !ok {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		ch := room.subscribe()
+		defer room.unsubscribe(ch)
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case board, ok := <-ch:
+				if !ok {
+					return false
+				}
+				c.SSEvent("board", board)
+				return true
+			case <-c.Request.Context().Done():
+				return false
+			}
+		})
+	})
+	r.GET("/api/puzzle/today", func(c *gin.Context) {
+		reveals := make(map[string]string)
+		for mode := range compModes {
+			if games := compGames(mode); len(games) > 0 {
+				reveals[mode] = games[0].reveal
+			}
+		}
+		c.JSON(http.StatusOK, getPuzzleDay(time.Now(), reveals))
+	})
+	// getFinishedShare builds the share of the session's finished game, and
+	// the key it is saved under. It answers the errors itself.
+	getFinishedShare := func(c *gin.Context) (share, string, bool) {
+		mode := c.DefaultQuery("mode", "classic")
+		// The composition and score puzzles are numbered after the day they
+		// were drawn for, the classic one after the player's day.
+		day := getDaily().day
+		var g game
+		var s share
+		var puzzle string
+		_, isComp := compModes[mode]
+		if (isComp || mode == "score") && !getDaily().ready() {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Today's games are still loading"})
+			return s, "", false
+		}
+		if isComp {
+			games := compGames(mode)
+			puzzle = games[0].slug
+			withGame(c, mode, puzzle, func(cur *game) { g = *cur })
+			s = newShare(mode, g, getCompGrid(g, puzzle, getClubs()), day)
+			s.Revealed, s.Total = getCompRevealed(mode, g, games)
+		} else if mode == "score" {
+			scoreGame := getDaily().score
+			puzzle = scoreGame[0].slug + ":" + scoreGame[1].slug
+			withGame(c, mode, puzzle, func(cur *game) { g = *cur })
+			s = newShare(mode, g, getScoreGrid(g, scoreGame), day)
+			s.Points = getScorePoints(g)
+		} else if _, ok := classicModes[mode]; ok {
+			var ok bool
+			if puzzle, day, ok = classicPuzzle(c); !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Your date is off"})
+				return s, "", false
+			}
+			withGame(c, mode, puzzle, func(cur *game) { g = *cur })
+			s = newShare(mode, g, g.rows, day)
+		} else {
+			c.Status(http.StatusNotFound)
+			return s, "", false
+		}
+		if !g.won && !g.lost {
+			c.JSON(http.StatusConflict, gin.H{"error": "Today's game isn't over yet"})
+			return s, "", false
+		}
+		return s, getSessionToken(c) + ":" + mode + ":" + puzzle, true
+	}
+	// The end of a game only shows the share, it is kept with a link when
+	// the player copies or tweets it.
+	r.GET("/api/share", func(c *gin.Context) {
+		if s, _, ok := getFinishedShare(c); ok {
+			s.Text = s.text()
+			c.JSON(http.StatusOK, s)
+		}
+	})
+	r.POST("/api/share", func(c *gin.Context) {
+		if s, key, ok := getFinishedShare(c); ok {
+			c.JSON(http.StatusOK, saveShare(key, s))
+		}
+	})
+	r.GET("/share/:id", func(c *gin.Context) {
+		s, ok := getShare(c.Param("id"))
+		if !ok {
+			c.Redirect(http.StatusFound, "/")
+			return
+		}
+		url := getBaseUrl(c) + "/share/" + s.ID
+		c.HTML(http.StatusOK, "share.html", gin.H{
+			"Title":       fmt.Sprintf("%s #%d", strings.TrimPrefix(shareTitles[s.Mode], "#"), s.Puzzle),
+			"Description": s.Text,
+			"Url":         url,
+			"Image":       url + "/card.png",
+			"Lines":       strings.Split(s.Text, "\n"),
+			"Play":        getModeUrl(s.Mode),
+		})
+	})
+	r.GET("/share/:id/card.png", func(c *gin.Context) {
+		s, ok := getShare(c.Param("id"))
+		if !ok {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		c.Header("Cache-Control", "public, max-age=86400")
+		c.Data(http.StatusOK, "image/png", buildShareCard(s))
+	})
+	r.GET("/groups", func(c *gin.Context) {
+		c.HTML(http.StatusOK, "groups.html", gin.H{"Groups": getGroupsOf(getSessionToken(c)), "Code": c.DefaultQuery("code", "")})
+	})
+	r.POST("/groups", func(c *gin.Context) {
+		token := getSessionToken(c)
+		nickname := strings.TrimSpace(c.PostForm("nickname"))
+		name := strings.TrimSpace(c.PostForm("name"))
+		code := strings.ToUpper(strings.TrimSpace(c.PostForm("code")))
+		if nickname == "" || (name == "" && code == "") {
+			c.HTML(http.StatusOK, "groups.html", gin.H{"Groups": getGroupsOf(token), "Code": code, "Error": "Please fill in every field"})
+			return
+		}
+		if code == "" {
+			g := createGroup(name, token, nickname)
+			c.Redirect(http.StatusSeeOther, "/groups/"+g.Code)
+			return
+		}
+		if _, ok := joinGroup(code, token, nickname); !ok {
+			c.HTML(http.StatusOK, "groups.html", gin.H{"Groups": getGroupsOf(token), "Code": code, "Error": "This invite code doesn't exist"})
+			return
+		}
+		c.Redirect(http.StatusSeeOther, "/groups/"+code)
+	})
+	r.GET("/groups/:code", func(c *gin.Context) {
+		g, ok := getGroup(c.Param("code"), getSessionToken(c))
+		if !ok {
+			c.Redirect(http.StatusFound, "/groups?code="+c.Param("code"))
+			return
+		}
+		c.HTML(http.StatusOK, "groups.html", gin.H{
+			"Group":   g,
+			"Weekly":  getStandings(g, getStartOfWeek(getToday())),
+			"AllTime": getStandings(g, time.Time{}),
+		})
+	})
+	r.GET("/account", func(c *gin.Context) {
+		token := getSessionToken(c)
+		a, ok := getAccount(token)
+		if !ok {
+			c.HTML(http.StatusOK, "account.html", gin.H{})
+			return
+		}
+		c.HTML(http.StatusOK, "account.html", gin.H{"Account": a, "Stats": getProfile(token)})
+	})
+	r.POST("/account/signup", func(c *gin.Context) {
+		a, err := signup(c.PostForm("username"), c.PostForm("password"), getSessionToken(c))
+		if err != nil {
+			c.HTML(http.StatusOK, "account.html", gin.H{"Error": err.Error()})
+			return
+		}
+		revokeLogin(c)
+		setSessionToken(c, newLogin(a.Token))
+		c.Redirect(http.StatusSeeOther, "/account")
+	})
+	r.POST("/account/login", func(c *gin.Context) {
+		a, err := login(c.PostForm("username"), c.PostForm("password"))
+		if err != nil {
+			c.HTML(http.StatusOK, "account.html", gin.H{"Error": err.Error()})
+			return
+		}
+		// Only an anonymous session is merged, logging in over another
+		// account leaves both as they are.
+		current := getSessionToken(c)
+		if _, ok := getAccount(current); !ok {
+			mergeSession(current, a.Token)
+		}
+		revokeLogin(c)
+		setSessionToken(c, newLogin(a.Token))
+		c.Redirect(http.StatusSeeOther, "/account")
+	})
+	r.POST("/account/logout", func(c *gin.Context) {
+		revokeLogin(c)
+		setSessionToken(c, newSessionToken())
+		c.Redirect(http.StatusSeeOther, "/account")
+	})
 	r.GET("/all-clubs", func(c *gin.Context) {
 		var res bytes.Buffer
-		for _, c := range allClubs {
+		for _, c := range getClubs() {
 			res.WriteString(fmt.Sprintf(`<option value="%s">%s</option>`, c.Slug, c.Name))
 		}
 		c.Data(http.StatusOK, "text/html; charset=utf-8", res.Bytes())
 	})
-	r.Run()
+	if settings.Admin.Password != "" {
+		admin := r.Group("/admin", gin.BasicAuth(gin.Accounts{"admin": settings.Admin.Password}))
+		admin.GET("", func(c *gin.Context) {
+			history, last := getRefreshes()
+			var message string
+			if kind := c.Query("started"); kind != "" {
+				message = "Refreshing the " + kind + ", reload in a minute"
+			}
+			c.HTML(http.StatusOK, "admin.html", gin.H{"Kinds": refreshKinds, "Last": last, "History": history, "Message": message})
+		})
+		admin.POST("/refresh", func(c *gin.Context) {
+			kind := c.Query("kind")
+			if kind != "clubs" && kind != "players" {
+				c.String(http.StatusBadRequest, "unknown kind")
+				return
+			}
+			go runRefresh(kind)
+			c.Redirect(http.StatusSeeOther, "/admin?started="+kind)
+		})
+	}
+	r.GET("/healthz", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	r.GET("/readyz", func(c *gin.Context) {
+		checks := getDaily().checks(getToday())
+		checks["players"] = len(getPool()) > 0
+		checks["classic"] = getClassicAnswer(getToday()) != ""
+		checks["clubs"] = len(getClubs()) > 0
+		status := http.StatusOK
+		for _, ok := range checks {
+			if !ok {
+				status = http.StatusServiceUnavailable
+			}
+		}
+		c.JSON(status, checks)
+	})
+
+	srv := &http.Server{
+		Addr:         fmt.Sprintf(":%d", settings.Server.Port),
+		Handler:      r,
+		ReadTimeout:  seconds(settings.Server.ReadTimeout),
+		WriteTimeout: seconds(settings.Server.WriteTimeout),
+		IdleTimeout:  seconds(settings.Server.IdleTimeout),
+	}
+	srv.RegisterOnShutdown(closeRooms)
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal("Couldn't start the server " + err.Error())
+		}
+	}()
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	fmt.Println("Shutting down")
+	ctx, cancel := context.WithCancel(context.Background())
+	if settings.Server.ShutdownTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, seconds(settings.Server.ShutdownTimeout))
+	}
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Println("Couldn't drain every connection " + err.Error())
+	}
+	flushResults()
+	flushShares()
 }
 
-func getRandomGameFromLastGameweek() formation {
+// getRandomGameFromLastGameweek picks a side of a game passing the policy,
+// the clubs in recent are left out.
+func getRandomGameFromLastGameweek(day time.Time, recent map[string]bool) (formation, error) {
 	rand.Seed(time.Now().UnixNano())
-	gamesId := getGamesFromGameweek(getLastGameWeek())
+	slug := getLastGameWeek(gamePolicy.fixtureOffset)
+	var gamesId []string
+	for _, c := range getGamesFromGameweek(slug) {
+		if gamePolicy.reject(c, recent) == "" {
+			gamesId = append(gamesId, c.id)
+		}
+	}
+	if len(gamesId) == 0 {
+		return formation{}, fmt.Errorf("no game of %s passes the selection policy", slug)
+	}
 	gameId := gamesId[rand.Intn(len(gamesId))]
-	return getGameInfos(gameId, rand.Intn(2) == 1)
+	ret := getGameInfos(gameId, rand.Intn(2) == 1)
+	ret.reveal = revealSchedule[day.Weekday()]
+	ret.seed = int64(day.Year()*1000 + day.YearDay())
+	return ret, nil
 }
 
 type league struct {
@@ -126,8 +775,13 @@ type competition struct {
 		Competition struct {
 			Clubs struct {
 				Nodes []struct {
-					Slug string `json:"slug"`
-					Name string `json:"name"`
+					Slug          string `json:"slug"`
+					Name          string `json:"name"`
+					PictureUrl    string `json:"pictureUrl"`
+					Subscriptions int    `json:"subscriptionsCount"`
+					Country       struct {
+						Code string `json:"code"`
+					} `json:"country"`
 				} `json:"nodes"`
 			} `json:"clubs"`
 		} `json:"competition"`
@@ -145,12 +799,30 @@ type playerInfos struct {
 		Player struct {
 			Age         int     `json:"age"`
 			Position    string  `json:"position"`
+			Detailed    string  `json:"detailedPosition"`
 			ShirtNumber int     `json:"shirtNumber"`
 			PictureUrl  string  `json:"pictureUrl"`
 			L5          float32 `json:"l5"`
 			L15         float32 `json:"l15"`
 			DisplayName string  `json:"displayName"`
-			ActiveClub  struct {
+			Foot        string  `json:"preferredFoot"`
+			Height      int     `json:"height"`
+			Stats       struct {
+				Appearances int `json:"appearances"`
+			} `json:"stats"`
+			CardSupply []struct {
+				Limited int `json:"limited"`
+			} `json:"cardSupply"`
+			LowestPriceCard struct {
+				LiveSingleSaleOffer struct {
+					ReceiverSide struct {
+						Amounts struct {
+							EurCents int `json:"eurCents"`
+						} `json:"amounts"`
+					} `json:"receiverSide"`
+				} `json:"liveSingleSaleOffer"`
+			} `json:"lowestPriceCard"`
+			ActiveClub struct {
 				PictureUrl     string `json:"pictureUrl"`
 				DomesticLeague struct {
 					Slug string `json:"slug"`
@@ -181,11 +853,16 @@ type games struct {
 				Games []struct {
 					Id             string `json:"id"`
 					CoverageStatus string `json:"coverageStatus"`
-					HomeTeam       struct {
-						SubscriptionsCount int `json:"subscriptionsCount"`
+					Competition    struct {
+						Slug string `json:"slug"`
+					} `json:"competition"`
+					HomeTeam struct {
+						Slug               string `json:"slug"`
+						SubscriptionsCount int    `json:"subscriptionsCount"`
 					} `json:"homeTeam"`
 					AwayTeam struct {
-						SubscriptionsCount int `json:"subscriptionsCount"`
+						Slug               string `json:"slug"`
+						SubscriptionsCount int    `json:"subscriptionsCount"`
 					} `json:"awayTeam"`
 				} `json:"games"`
 			} `json:"so5Fixture"`
@@ -196,6 +873,7 @@ type games struct {
 type playerinf struct {
 	Age              int
 	Position         string
+	DetailedPosition string
 	ShirtNumber      int
 	Club             string
 	ClubLeague       string
@@ -206,11 +884,21 @@ type playerinf struct {
 	PicUrl           string
 	Name             string
 	NationalTeamCode string
+	Foot             string
+	Height           int
+	Appearances      int
+	Supply           int
+	PriceBand        int
 }
 
 type gameinfos struct {
 	Football struct {
 		Game struct {
+			HomeGoals   int `json:"homeGoals"`
+			AwayGoals   int `json:"awayGoals"`
+			Competition struct {
+				DisplayName string `json:"displayName"`
+			} `json:"competition"`
 			HomeTeam struct {
 				Name       string `json:"name"`
 				PictureUrl string `json:"pictureUrl"`
@@ -218,13 +906,28 @@ type gameinfos struct {
 			} `json:"homeTeam"`
 			HomeFormation struct {
 				StartingLineUp [][]struct {
-					Country struct {
+					Position    string `json:"position"`
+					ShirtNumber int    `json:"shirtNumber"`
+					Country     struct {
 						FlagUrl string `json:"flagUrl"`
 					} `json:"country"`
 					So5Scores []struct {
 						Score float32 `json:"score"`
 					} `json:"so5Scores"`
 				} `json:"startingLineup"`
+				Bench []struct {
+					Position    string `json:"position"`
+					ShirtNumber int    `json:"shirtNumber"`
+					Country     struct {
+						FlagUrl string `json:"flagUrl"`
+					} `json:"country"`
+					So5Scores []struct {
+						Score           float32 `json:"score"`
+						PlayerGameStats struct {
+							MinsPlayed int `json:"minsPlayed"`
+						} `json:"playerGameStats"`
+					} `json:"so5Scores"`
+				} `json:"bench"`
 			} `json:"homeFormation"`
 			AwayTeam struct {
 				Name       string `json:"name"`
@@ -233,13 +936,28 @@ type gameinfos struct {
 			} `json:"awayTeam"`
 			AwayFormation struct {
 				StartingLineUp [][]struct {
-					Country struct {
+					Position    string `json:"position"`
+					ShirtNumber int    `json:"shirtNumber"`
+					Country     struct {
 						FlagUrl string `json:"flagUrl"`
 					} `json:"country"`
 					So5Scores []struct {
 						Score float32 `json:"score"`
 					} `json:"so5Scores"`
 				} `json:"startingLineup"`
+				Bench []struct {
+					Position    string `json:"position"`
+					ShirtNumber int    `json:"shirtNumber"`
+					Country     struct {
+						FlagUrl string `json:"flagUrl"`
+					} `json:"country"`
+					So5Scores []struct {
+						Score           float32 `json:"score"`
+						PlayerGameStats struct {
+							MinsPlayed int `json:"minsPlayed"`
+						} `json:"playerGameStats"`
+					} `json:"so5Scores"`
+				} `json:"bench"`
 			} `json:"awayFormation"`
 		} `json:"game"`
 	} `json:"football"`
@@ -250,11 +968,30 @@ type formation struct {
 	pictureUrl string
 	slug       string
 	players    [][]compplayers
+	bench      []compplayers
+	shape      string
+	reveal     string
+	seed       int64
+	match      matchinfos
+	// gameweek counts back from the last gameweek, it is 0 for the game of
+	// the last one.
+	gameweek int
+}
+
+type matchinfos struct {
+	isHome             bool
+	opponentName       string
+	opponentPictureUrl string
+	goalsFor           int
+	goalsAgainst       int
+	competition        string
 }
 
 type compplayers struct {
-	score      float32
-	countryUrl string
+	score       float32
+	countryUrl  string
+	position    string
+	shirtNumber int
 }
 
 type color string
@@ -284,19 +1021,30 @@ func getShortPosition(pos string) string {
 	case "Forward":
 		return "FWD"
 	}
-	return "ERR"
+	return "?"
 }
 
-type Continent int
+var detailedPositions = map[string]string{
+	"GOALKEEPER":         "GK",
+	"CENTRE_BACK":        "CB",
+	"LEFT_BACK":          "LB",
+	"RIGHT_BACK":         "RB",
+	"LEFT_WING_BACK":     "LWB",
+	"RIGHT_WING_BACK":    "RWB",
+	"DEFENSIVE_MIDFIELD": "DM",
+	"CENTRAL_MIDFIELD":   "CM",
+	"ATTACKING_MIDFIELD": "AM",
+	"LEFT_MIDFIELD":      "LM",
+	"RIGHT_MIDFIELD":     "RM",
+	"LEFT_WINGER":        "LW",
+	"RIGHT_WINGER":       "RW",
+	"SECOND_STRIKER":     "SS",
+	"CENTRE_FORWARD":     "ST",
+}
 
-const (
-	Asia Continent = iota
-	Europe
-	Africa
-	Oceania
-	Americas
-	Other
-)
+func getDetailedPosition(pos string) string {
+	return detailedPositions[pos]
+}
 
 func dump(filename string, data any) {
 	var buf bytes.Buffer
@@ -305,7 +1053,7 @@ func dump(filename string, data any) {
 	if err != nil {
 		log.Fatal("Error encoding cache" + err.Error())
 	}
-	f, err := os.Create(filename + ".bin")
+	f, err := os.Create(filepath.Join(settings.Data.Dir, filename+".bin"))
 	if err != nil {
 		log.Fatal("Couldn't open cache file " + err.Error())
 	}
@@ -315,7 +1063,7 @@ func dump(filename string, data any) {
 
 func pick[K interface{}](filename string) (K, error) {
 	var ret K
-	f, err := os.ReadFile(filename + ".bin")
+	f, err := os.ReadFile(filepath.Join(settings.Data.Dir, filename+".bin"))
 	if err != nil {
 		return ret, err
 	}
@@ -327,37 +1075,135 @@ func pick[K interface{}](filename string) (K, error) {
 	return ret, nil
 }
 
-func testClub(slug1 string, trys int, today formation) (bytes.Buffer, bool) {
+func testClub(mode string, g game, games []formation, clubs []clubinfos) bytes.Buffer {
 	var ret bytes.Buffer
-	winner := slug1 == today.slug
-	i := 0
-	ret.WriteString(`<div class="field">`)
-	for _, line := range today.players {
-		ret.WriteString(`<div class="row">`)
-		for _, player := range line {
-			if i >= trys && !winner {
-				ret.WriteString(`<div class="player"><img src="` + player.countryUrl + `" class="hidden"><div class="score" style="background-color:` + getColorOfNote(player.score) + `">` + fmt.Sprintf("%v", player.score) + `</div></div>`)
-			} else {
-				ret.WriteString(`<div class="player"><img src="` + player.countryUrl + `"><div class="score" style="background-color:` + getColorOfNote(player.score) + `">` + fmt.Sprintf("%v", player.score) + `</div></div>`)
-			}
-			i++
+	today := games[0]
+	visible, bench := getCompVisibility(mode, games, g.guesses)
+	over := g.won || g.lost
+	ret.Write(buildCompHints(g, today))
+	ret.WriteString(`<div class="fields">`)
+	for n, f := range games {
+		ret.WriteString(`<div class="gameweek">`)
+		if len(games) > 1 {
+			ret.WriteString(fmt.Sprintf(`<h3>%s</h3>`, getGameweekLabel(f.gameweek)))
 		}
+		if over || usedCompHint(g, "Formation") {
+			ret.WriteString(`<h2 class="shape">` + f.shape + `</h2>`)
+		}
+		ret.Write(buildField(f, visible[n], over, over || usedCompHint(g, "Positions")))
 		ret.WriteString(`</div>`)
 	}
 	ret.WriteString(`</div>`)
-	if winner {
+	if compModes[mode].bench && len(today.bench) > 0 {
+		ret.WriteString(`<div class="bench">`)
+		for i, player := range today.bench {
+			ret.Write(buildCompPlayer(player, bench[i], over, false))
+		}
+		ret.WriteString(`</div>`)
+	}
+	if !over {
+		ret.WriteString(buildCompHintButton(mode, g))
+	}
+	if target, ok := getClub(clubs, today.slug); ok && len(g.history) > 0 {
+		ret.Write(buildClubHistory(target, g.history, clubs))
+	}
+	if g.won {
 		ret.WriteString(fmt.Sprintf(`
 			<div class="winner" id="winner">
 				<h2>Good Job ! You found <span>%s</span> in %d trys ! <a href="/classic">Now try the classic version !</a>
 			</div>
-		`, today.name, trys))
-		numberOfFound++
+		`, today.name, g.guesses))
+	} else if g.lost {
+		ret.WriteString(fmt.Sprintf(`
+			<div class="loser" id="loser">
+				<img src="%s"/>
+				<h2>The club was <span>%s</span> ! <a href="/classic">Now try the classic version !</a>
+			</div>
+		`, today.pictureUrl, today.name))
+	}
+	return ret
+}
+
+func buildField(f formation, visible [][]visibility, over, positions bool) []byte {
+	var ret bytes.Buffer
+	ret.WriteString(`<div class="field">`)
+	for l, line := range f.players {
+		ret.WriteString(`<div class="row">`)
+		for i, player := range line {
+			ret.Write(buildCompPlayer(player, visible[l][i], over, positions))
+		}
+		ret.WriteString(`</div>`)
+	}
+	ret.WriteString(`</div>`)
+	return ret.Bytes()
+}
+
+func buildCompPlayer(player compplayers, v visibility, over, positions bool) []byte {
+	var ret bytes.Buffer
+	ret.WriteString(`<div class="player">`)
+	if v.flag || over {
+		ret.WriteString(`<img src="` + player.countryUrl + `">`)
+	} else {
+		ret.WriteString(`<img src="` + player.countryUrl + `" class="hidden">`)
+	}
+	if v.score || over {
+		ret.WriteString(`<div class="score" style="background-color:` + getColorOfNote(player.score) + `">` + fmt.Sprintf("%v", player.score) + `</div>`)
+	} else {
+		ret.WriteString(`<div class="score hidden-score">?</div>`)
+	}
+	if positions {
+		ret.WriteString(fmt.Sprintf(`<div class="position">%s #%d</div>`, player.position, player.shirtNumber))
 	}
-	return ret, winner
+	ret.WriteString(`</div>`)
+	return ret.Bytes()
+}
+
+func getClub(clubs []clubinfos, slug string) (clubinfos, bool) {
+	for _, c := range clubs {
+		if c.Slug == slug {
+			return c, true
+		}
+	}
+	return clubinfos{}, false
+}
+
+func revealPlayer(slug string) []byte {
+	ch := make(chan playerinf, 1)
+	getPlayerInformations(slug, ch)
+	p := <-ch
+	return []byte(fmt.Sprintf(`
+		<div class="loser" id="loser">
+			<div class="card">
+				<img src="%s"/>
+				<img src="%s"/>
+				<img src="%s"/>
+				<div>%s</div>
+			</div>
+			<h2>The player was <span>%s</span> ! <a href="/comp">Now try the composition version !</a>
+		</div>
+	`, p.PicUrl, p.Club, p.NationalTeam, p.Position, p.Name))
 }
 
-func comparePlayerInformations(slug1, slug2 string, trys int) (bytes.Buffer, bool) {
+func comparePlayerInformations(slug1, slug2 string, trys, hints int, cols []column) (bytes.Buffer, []color, bool, bool) {
 	var ret bytes.Buffer
+	p2, cells, ok := compareRow(slug1, slug2, cols)
+	if !ok {
+		ret.WriteString(`<div class="error" id="error">Error : Please pick a player in the list</div>`)
+		return ret, nil, false, false
+	}
+	winner := slug1 == slug2
+	ret.Write(buildRow(p2, cells))
+	if winner {
+		ret.WriteString(fmt.Sprintf(`
+			<div class="winner" id="winner" data-hints="%d">
+				<h2>Good Job ! You found <span>%s</span> in %d trys%s ! <a href="/comp">Now try the composition version !</a>
+			</div>
+		`, hints, p2.Name, trys, getHintsText(hints)))
+	}
+	return ret, getColors(cells), winner, true
+}
+
+func compareRow(slug1, slug2 string, cols []column) (playerinf, []cell, bool) {
 	ch := make(chan playerinf, 2)
 	go getPlayerInformations(slug1, ch)
 	go getPlayerInformations(slug2, ch)
@@ -367,69 +1213,24 @@ func comparePlayerInformations(slug1, slug2 string, trys int) (bytes.Buffer, boo
 		p1, p2 = p2, p1
 	}
 	if p2.Age == 0 {
-		ret.WriteString(`<div class="error" id="error">Error : Please pick a player in the list</div>`)
-		return ret, false
+		return p2, nil, false
 	}
-	ret.WriteString(`<div class="row">`)
-	winner := slug1 == slug2
-	ret.WriteString(`<div><img src="` + p2.PicUrl + `" title="` + p2.Name + `"/></div>`)
-	if p1.Age == p2.Age {
-		ret.Write(buildTextDiv(GREEN, strconv.Itoa(p2.Age), NONE))
-	} else if p1.Age > p2.Age {
-		ret.Write(buildTextDiv(RED, strconv.Itoa(p2.Age), OVER))
-	} else if p1.Age < p2.Age {
-		ret.Write(buildTextDiv(RED, strconv.Itoa(p2.Age), UNDER))
-	}
-	if p1.Club == p2.Club {
-		ret.Write(buildTextDiv(GREEN, `<img src="`+p2.Club+`"/>`, NONE))
-	} else if p1.ClubLeague == p2.ClubLeague {
-		ret.Write(buildTextDiv(YELLOW, `<img src="`+p2.Club+`"/>`, NONE))
-	} else {
-		ret.Write(buildTextDiv(RED, `<img src="`+p2.Club+`"/>`, NONE))
-	}
-	if p1.NationalTeam == p2.NationalTeam {
-		ret.Write(buildTextDiv(GREEN, `<img src="`+p2.NationalTeam+`"/>`, NONE))
-	} else if getContinent(p1.NationalTeamCode) == getContinent(p2.NationalTeamCode) {
-		ret.Write(buildTextDiv(YELLOW, `<img src="`+p2.NationalTeam+`"/>`, NONE))
-	} else {
-		ret.Write(buildTextDiv(RED, `<img src="`+p2.NationalTeam+`"/>`, NONE))
-	}
-	if p1.ShirtNumber == p2.ShirtNumber {
-		ret.Write(buildTextDiv(GREEN, strconv.Itoa(p2.ShirtNumber), NONE))
-	} else if p1.ShirtNumber > p2.ShirtNumber {
-		ret.Write(buildTextDiv(RED, strconv.Itoa(p2.ShirtNumber), OVER))
-	} else if p1.ShirtNumber < p2.ShirtNumber {
-		ret.Write(buildTextDiv(RED, strconv.Itoa(p2.ShirtNumber), UNDER))
+	var cells []cell
+	for _, col := range cols {
+		cells = append(cells, col.render(p1, p2))
 	}
-	if p1.Position == p2.Position {
-		ret.Write(buildTextDiv(GREEN, p2.Position, NONE))
-	} else {
-		ret.Write(buildTextDiv(RED, p2.Position, NONE))
-	}
-	if p1.L5 == p2.L5 {
-		ret.Write(buildTextDiv(GREEN, strconv.Itoa(p2.L5), NONE))
-	} else if p1.L5 > p2.L5 {
-		ret.Write(buildTextDiv(RED, strconv.Itoa(p2.L5), OVER))
-	} else if p1.L5 < p2.L5 {
-		ret.Write(buildTextDiv(RED, strconv.Itoa(p2.L5), UNDER))
-	}
-	if p1.L15 == p2.L15 {
-		ret.Write(buildTextDiv(GREEN, strconv.Itoa(p2.L15), NONE))
-	} else if p1.L15 > p2.L15 {
-		ret.Write(buildTextDiv(RED, strconv.Itoa(p2.L15), OVER))
-	} else if p1.L15 < p2.L15 {
-		ret.Write(buildTextDiv(RED, strconv.Itoa(p2.L15), UNDER))
+	return p2, cells, true
+}
+
+func buildRow(p playerinf, cells []cell) []byte {
+	var ret bytes.Buffer
+	ret.WriteString(`<div class="row">`)
+	ret.WriteString(`<div><img src="` + p.PicUrl + `" title="` + p.Name + `"/></div>`)
+	for _, c := range cells {
+		ret.Write(c.html())
 	}
 	ret.WriteString("</div>")
-	if winner {
-		ret.WriteString(fmt.Sprintf(`
-			<div class="winner" id="winner">
-				<h2>Good Job ! You found <span>%s</span> in %d trys ! <a href="/comp">Now try the composition version !</a>
-			</div>
-		`, p2.Name, trys))
-		numberOfFound++
-	}
-	return ret, winner
+	return ret.Bytes()
 }
 
 func getColorOfNote(note float32) string {
@@ -449,6 +1250,14 @@ func getColorOfNote(note float32) string {
 	return "#CD4115"
 }
 
+func getColors(cells []cell) []color {
+	var ret []color
+	for _, c := range cells {
+		ret = append(ret, c.color)
+	}
+	return ret
+}
+
 func buildTextDiv(c color, content string, a arrow) []byte {
 	var ret bytes.Buffer
 	ret.WriteString(fmt.Sprintf(`<div class="%s">%s`, c, content))
@@ -469,16 +1278,34 @@ func buildTextDiv(c color, content string, a arrow) []byte {
 
 func getPlayerInformations(slug string, res chan playerinf) {
 	q := graphql.NewRequest(`
-	query($slug: String!) {
+	query($slug: String!, $season: Int!) {
 		football {
 			player(slug:$slug) {
 				age
 				position
+				detailedPosition
 				shirtNumber
 				pictureUrl
 				displayName
+				preferredFoot
+				height
 				l5: averageScore(type:LAST_FIVE_SO5_AVERAGE_SCORE)
         		l15: averageScore(type:LAST_FIFTEEN_SO5_AVERAGE_SCORE)
+				stats(seasonStartYear:$season) {
+					appearances
+				}
+				cardSupply {
+					limited
+				}
+				lowestPriceCard(rarity:limited) {
+					liveSingleSaleOffer {
+						receiverSide {
+							amounts {
+								eurCents
+							}
+						}
+					}
+				}
 				activeClub {
 					pictureUrl
 					domesticLeague {
@@ -494,6 +1321,7 @@ func getPlayerInformations(slug string, res chan playerinf) {
 	}
 	`)
 	q.Var("slug", slug)
+	q.Var("season", getSeasonStartYear(time.Now()))
 	player, err := callSorareApi[playerInfos](q)
 	var ret playerinf
 	if err == nil {
@@ -502,6 +1330,7 @@ func getPlayerInformations(slug string, res chan playerinf) {
 		ret.ClubLeague = player.Football.Player.ActiveClub.DomesticLeague.Slug
 		ret.NationalTeam = player.Football.Player.Country.FlagUrl
 		ret.Position = getShortPosition(player.Football.Player.Position)
+		ret.DetailedPosition = getDetailedPosition(player.Football.Player.Detailed)
 		ret.ShirtNumber = player.Football.Player.ShirtNumber
 		ret.Slug = slug
 		ret.L5 = int(player.Football.Player.L5)
@@ -509,72 +1338,86 @@ func getPlayerInformations(slug string, res chan playerinf) {
 		ret.Name = player.Football.Player.DisplayName
 		ret.PicUrl = player.Football.Player.PictureUrl
 		ret.NationalTeamCode = strings.ToUpper(player.Football.Player.Country.Code)
+		ret.Foot = strings.ToUpper(player.Football.Player.Foot)
+		ret.Height = player.Football.Player.Height
+		ret.Appearances = player.Football.Player.Stats.Appearances
+		for _, s := range player.Football.Player.CardSupply {
+			ret.Supply += s.Limited
+		}
+		ret.PriceBand = getPriceBand(player.Football.Player.LowestPriceCard.LiveSingleSaleOffer.ReceiverSide.Amounts.EurCents)
 	}
 	res <- ret
 }
 
-func getAllClubs() []clubinfos {
-	leagues := getAllLeagues()
+// getAllClubs fails if any league can't be fetched, a partial list would
+// drop clubs that may be today's answer.
+func getAllClubs() ([]clubinfos, error) {
+	leagues, err := getAllLeagues()
+	if err != nil {
+		return nil, err
+	}
 	var clubs []clubinfos
+	var firstErr error
 	wg := sync.WaitGroup{}
 	mu := &sync.Mutex{}
 	for _, l := range leagues {
 		wg.Add(1)
 		go func(slug string) {
 			defer wg.Done()
-			c := getAllClubsFromCompetition(slug)
+			c, err := getAllClubsFromCompetition(slug)
 			mu.Lock()
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
 			clubs = append(clubs, c...)
 			mu.Unlock()
 		}(l)
 	}
 	wg.Wait()
-	return clubs
+	return clubs, firstErr
 }
 
-func getNMostSubscribedPlayers(n int) []playersub {
-	leagues := getAllLeagues()
-	var clubs []string
+func getNMostSubscribedPlayers(n int) ([]playersub, error) {
+	all, err := getAllClubs()
+	if err != nil {
+		return nil, err
+	}
+	var players []playersub
+	var firstErr error
 	wg := sync.WaitGroup{}
 	mu := &sync.Mutex{}
-	for _, l := range leagues {
+	for _, c := range all {
 		wg.Add(1)
 		go func(slug string) {
 			defer wg.Done()
-			c := getAllClubsFromCompetition(slug)
+			p, err := getPlayersFromClub(slug)
 			mu.Lock()
-			for _, cl := range c {
-				clubs = append(clubs, cl.Slug)
+			if err != nil && firstErr == nil {
+				firstErr = err
 			}
-			mu.Unlock()
-		}(l)
-	}
-	wg.Wait()
-	var players []playersub
-	for _, c := range clubs {
-		wg.Add(1)
-		go func(slug string) {
-			defer wg.Done()
-			p := getPlayersFromClub(slug)
-			mu.Lock()
 			players = append(players, p...)
 			mu.Unlock()
-		}(c)
+		}(c.Slug)
 	}
 	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if len(players) < n {
+		return nil, fmt.Errorf("only %d players for a pool of %d", len(players), n)
+	}
 	sort.Slice(players, func(i, j int) bool {
 		return players[i].Subscriptions > players[j].Subscriptions
 	})
 	players = players[:n]
 	rand.Seed(time.Now().UnixNano())
 	rand.Shuffle(len(players), func(i, j int) { players[i], players[j] = players[j], players[i] })
-	return players
+	return players, nil
 }
 
 func callSorareApi[K interface{}](req *graphql.Request) (K, error) {
-	api_key := os.Getenv("SORARE_API_KEY")
-	client := graphql.NewClient("https://api.sorare.com/graphql")
-	req.Header.Set("APIKEY", api_key)
+	client := graphql.NewClient(settings.Sorare.Url)
+	req.Header.Set("APIKEY", settings.Sorare.ApiKey)
 	var ret K
 	if err := client.Run(context.Background(), req, &ret); err != nil {
 		return ret, err
@@ -582,7 +1425,7 @@ func callSorareApi[K interface{}](req *graphql.Request) (K, error) {
 	return ret, nil
 }
 
-func getAllLeagues() []string {
+func getAllLeagues() ([]string, error) {
 	q := `
 	{
 		football {
@@ -594,22 +1437,32 @@ func getAllLeagues() []string {
 		}
 	  }
 	`
-	leagues, _ := callSorareApi[league](graphql.NewRequest(q))
+	leagues, err := callSorareApi[league](graphql.NewRequest(q))
+	if err != nil {
+		return nil, err
+	}
 	var ret []string
 	for _, l := range leagues.Football.Leagues {
 		if l.Format == "DOMESTIC_LEAGUE" {
 			ret = append(ret, l.Slug)
 		}
 	}
-	return ret
+	if len(ret) == 0 {
+		return nil, errors.New("Sorare gave no leagues")
+	}
+	return ret, nil
 }
 
 type clubinfos struct {
-	Slug string
-	Name string
+	Slug          string
+	Name          string
+	PictureUrl    string
+	League        string
+	Country       string
+	Subscriptions int
 }
 
-func getAllClubsFromCompetition(slug string) []clubinfos {
+func getAllClubsFromCompetition(slug string) ([]clubinfos, error) {
 	q := graphql.NewRequest(`
 	query($slug: String!) {
 		football {
@@ -618,6 +1471,11 @@ func getAllClubsFromCompetition(slug string) []clubinfos {
 					nodes {
 						slug
 						name
+						pictureUrl
+						subscriptionsCount
+						country {
+							code
+						}
 					}
 				}
 			}
@@ -625,15 +1483,25 @@ func getAllClubsFromCompetition(slug string) []clubinfos {
 	}
 	`)
 	q.Var("slug", slug)
-	res, _ := callSorareApi[competition](q)
+	res, err := callSorareApi[competition](q)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", slug, err)
+	}
 	var ret []clubinfos
 	for _, c := range res.Football.Competition.Clubs.Nodes {
-		ret = append(ret, clubinfos{Slug: c.Slug, Name: c.Name})
+		ret = append(ret, clubinfos{
+			Slug:          c.Slug,
+			Name:          c.Name,
+			PictureUrl:    c.PictureUrl,
+			League:        slug,
+			Country:       strings.ToUpper(c.Country.Code),
+			Subscriptions: c.Subscriptions,
+		})
 	}
-	return ret
+	return ret, nil
 }
 
-func getPlayersFromClub(slug string) []playersub {
+func getPlayersFromClub(slug string) ([]playersub, error) {
 	q := graphql.NewRequest(`
 	query($slug: String!) {
 		football {
@@ -653,33 +1521,49 @@ func getPlayersFromClub(slug string) []playersub {
 	}
 	`)
 	q.Var("slug", slug)
-	res, _ := callSorareApi[club](q)
+	res, err := callSorareApi[club](q)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", slug, err)
+	}
 	var ret []playersub
 	for _, p := range res.Football.Club.ActivePlayers.Nodes {
 		if len(p.CardSupply) > 0 {
 			ret = append(ret, playersub{Slug: p.Slug, Subscriptions: p.Subscriptions, DisplayName: p.DisplayName})
 		}
 	}
-	return ret
+	return ret, nil
 }
 
-func getLastGameWeek() string {
+func getLastGameWeek(offset int) string {
+	slugs := getLastGameWeeks(offset, 1)
+	if len(slugs) == 0 {
+		return ""
+	}
+	return slugs[0]
+}
+
+func getLastGameWeeks(offset, n int) []string {
 	q := graphql.NewRequest(`
-		query {
+		query($first: Int!) {
 			football {
 				so5 {
-					featuredSo5Fixtures(first:3) {
+					featuredSo5Fixtures(first:$first) {
 						slug
 					}
 				}
 			}
 		}
 	`)
+	q.Var("first", offset+n)
 	res, _ := callSorareApi[featured](q)
-	return res.Football.So5.FeaturedSo5Fixtures[2].Slug
+	var ret []string
+	for i := offset; i < len(res.Football.So5.FeaturedSo5Fixtures); i++ {
+		ret = append(ret, res.Football.So5.FeaturedSo5Fixtures[i].Slug)
+	}
+	return ret
 }
 
-func getGamesFromGameweek(slug string) []string {
+func getGamesFromGameweek(slug string) []candidate {
 	q := graphql.NewRequest(`
 		query($slug: String!) {
 			football {
@@ -688,13 +1572,18 @@ func getGamesFromGameweek(slug string) []string {
 						games {
 							id
 							coverageStatus
+							competition {
+								slug
+							}
 							homeTeam {
 								... on Club {
+								  slug
 								  subscriptionsCount
 								}
 							  }
 							awayTeam {
 								... on Club {
+								  slug
 								  subscriptionsCount
 								}
 							}
@@ -706,11 +1595,17 @@ func getGamesFromGameweek(slug string) []string {
 	`)
 	q.Var("slug", slug)
 	res, _ := callSorareApi[games](q)
-	var ret []string
+	var ret []candidate
 	for _, g := range res.Football.So5.So5Fixture.Games {
-		if g.CoverageStatus == "FULL" && g.HomeTeam.SubscriptionsCount > 1000 && g.AwayTeam.SubscriptionsCount > 1000 {
-			ret = append(ret, g.Id[5:])
-		}
+		ret = append(ret, candidate{
+			id:       g.Id[5:],
+			coverage: g.CoverageStatus,
+			league:   g.Competition.Slug,
+			home:     g.HomeTeam.Slug,
+			away:     g.AwayTeam.Slug,
+			homeSubs: g.HomeTeam.SubscriptionsCount,
+			awaySubs: g.AwayTeam.SubscriptionsCount,
+		})
 	}
 	return ret
 }
@@ -720,6 +1615,11 @@ func getGameInfos(id string, isHome bool) formation {
 		query($slug: ID!) {
 			football {
 				game(id:$slug) {
+					homeGoals
+					awayGoals
+					competition {
+						displayName
+					}
 					homeTeam {
 						... on Club {
 							name
@@ -736,6 +1636,8 @@ func getGameInfos(id string, isHome bool) formation {
 					}
 					homeFormation {
 						startingLineup {
+							position
+							shirtNumber
 							country {
 								flagUrl
 							}
@@ -743,9 +1645,24 @@ func getGameInfos(id string, isHome bool) formation {
 								score
 							}
 						}
+						bench {
+							position
+							shirtNumber
+							country {
+								flagUrl
+							}
+							so5Scores(last:1) {
+								score
+								playerGameStats {
+									minsPlayed
+								}
+							}
+						}
 					}
 					awayFormation {
 						startingLineup {
+							position
+							shirtNumber
 							country {
 								flagUrl
 							}
@@ -753,6 +1670,19 @@ func getGameInfos(id string, isHome bool) formation {
 								score
 							}
 						}
+						bench {
+							position
+							shirtNumber
+							country {
+								flagUrl
+							}
+							so5Scores(last:1) {
+								score
+								playerGameStats {
+									minsPlayed
+								}
+							}
+						}
 					}
 				}
 			}
@@ -761,7 +1691,13 @@ func getGameInfos(id string, isHome bool) formation {
 	q.Var("slug", id)
 	res, _ := callSorareApi[gameinfos](q)
 	var ret formation
+	ret.match.isHome = isHome
+	ret.match.competition = res.Football.Game.Competition.DisplayName
 	if isHome {
+		ret.match.opponentName = res.Football.Game.AwayTeam.Name
+		ret.match.opponentPictureUrl = res.Football.Game.AwayTeam.PictureUrl
+		ret.match.goalsFor = res.Football.Game.HomeGoals
+		ret.match.goalsAgainst = res.Football.Game.AwayGoals
 		ret.name = res.Football.Game.HomeTeam.Name
 		ret.slug = res.Football.Game.HomeTeam.Slug
 		ret.pictureUrl = res.Football.Game.HomeTeam.PictureUrl
@@ -769,11 +1705,25 @@ func getGameInfos(id string, isHome bool) formation {
 		for _, l := range res.Football.Game.HomeFormation.StartingLineUp {
 			line := make([]compplayers, 0)
 			for _, p := range l {
-				line = append(line, compplayers{score: p.So5Scores[0].Score, countryUrl: p.Country.FlagUrl})
+				// Games without full coverage can miss scores.
+				var score float32
+				if len(p.So5Scores) > 0 {
+					score = p.So5Scores[0].Score
+				}
+				line = append(line, compplayers{score: score, countryUrl: p.Country.FlagUrl, position: getShortPosition(p.Position), shirtNumber: p.ShirtNumber})
 			}
 			ret.players = append(ret.players, line)
 		}
+		for _, p := range res.Football.Game.HomeFormation.Bench {
+			if len(p.So5Scores) > 0 && p.So5Scores[0].PlayerGameStats.MinsPlayed > 0 {
+				ret.bench = append(ret.bench, compplayers{score: p.So5Scores[0].Score, countryUrl: p.Country.FlagUrl, position: getShortPosition(p.Position), shirtNumber: p.ShirtNumber})
+			}
+		}
 	} else {
+		ret.match.opponentName = res.Football.Game.HomeTeam.Name
+		ret.match.opponentPictureUrl = res.Football.Game.HomeTeam.PictureUrl
+		ret.match.goalsFor = res.Football.Game.AwayGoals
+		ret.match.goalsAgainst = res.Football.Game.HomeGoals
 		ret.name = res.Football.Game.AwayTeam.Name
 		ret.slug = res.Football.Game.AwayTeam.Slug
 		ret.pictureUrl = res.Football.Game.AwayTeam.PictureUrl
@@ -781,479 +1731,99 @@ func getGameInfos(id string, isHome bool) formation {
 		for _, l := range res.Football.Game.AwayFormation.StartingLineUp {
 			line := make([]compplayers, 0)
 			for _, p := range l {
-				line = append(line, compplayers{score: p.So5Scores[0].Score, countryUrl: p.Country.FlagUrl})
+				// Games without full coverage can miss scores.
+				var score float32
+				if len(p.So5Scores) > 0 {
+					score = p.So5Scores[0].Score
+				}
+				line = append(line, compplayers{score: score, countryUrl: p.Country.FlagUrl, position: getShortPosition(p.Position), shirtNumber: p.ShirtNumber})
 			}
 			ret.players = append(ret.players, line)
 		}
+		for _, p := range res.Football.Game.AwayFormation.Bench {
+			if len(p.So5Scores) > 0 && p.So5Scores[0].PlayerGameStats.MinsPlayed > 0 {
+				ret.bench = append(ret.bench, compplayers{score: p.So5Scores[0].Score, countryUrl: p.Country.FlagUrl, position: getShortPosition(p.Position), shirtNumber: p.ShirtNumber})
+			}
+		}
+	}
+	ret.shape = getFormationShape(id, isHome)
+	if ret.shape == "" {
+		ret.shape = getShape(ret.players)
 	}
 	return ret
 }
 
-/*
-GENERATED CODE
-*/
-func getContinent(code string) Continent {
-	switch code {
-	case "AF":
-		return Asia
-	case "AL":
-		return Europe
-	case "DZ":
-		return Africa
-	case "AS":
-		return Oceania
-	case "AD":
-		return Europe
-	case "AO":
-		return Africa
-	case "AI":
-		return Americas
-	case "AG":
-		return Americas
-	case "AR":
-		return Americas
-	case "AM":
-		return Asia
-	case "AW":
-		return Americas
-	case "AU":
-		return Oceania
-	case "AT":
-		return Europe
-	case "AZ":
-		return Asia
-	case "BS":
-		return Americas
-	case "BH":
-		return Asia
-	case "BD":
-		return Asia
-	case "BB":
-		return Americas
-	case "BY":
-		return Europe
-	case "BE":
-		return Europe
-	case "BZ":
-		return Americas
-	case "BJ":
-		return Africa
-	case "BM":
-		return Americas
-	case "BT":
-		return Asia
-	case "BO":
-		return Americas
-	case "BA":
-		return Europe
-	case "BW":
-		return Africa
-	case "BR":
-		return Americas
-	case "VG":
-		return Americas
-	case "BN":
-		return Asia
-	case "BG":
-		return Europe
-	case "BF":
-		return Africa
-	case "BI":
-		return Africa
-	case "KH":
-		return Asia
-	case "CM":
-		return Africa
-	case "CA":
-		return Americas
-	case "CV":
-		return Africa
-	case "KY":
-		return Americas
-	case "CF":
-		return Africa
-	case "TD":
-		return Africa
-	case "CL":
-		return Americas
-	case "CN":
-		return Asia
-	case "CX":
-		return Asia
-	case "CC":
-		return Asia
-	case "CO":
-		return Americas
-	case "KM":
-		return Africa
-	case "CG":
-		return Africa
-	case "CK":
-		return Oceania
-	case "CR":
-		return Americas
-	case "CI":
-		return Africa
-	case "HR":
-		return Europe
-	case "CU":
-		return Americas
-	case "CY":
-		return Asia
-	case "CZ":
-		return Europe
-	case "DK":
-		return Europe
-	case "DJ":
-		return Africa
-	case "DM":
-		return Americas
-	case "DO":
-		return Americas
-	case "EC":
-		return Americas
-	case "EG":
-		return Africa
-	case "SV":
-		return Americas
-	case "GQ":
-		return Africa
-	case "ER":
-		return Africa
-	case "EE":
-		return Europe
-	case "ET":
-		return Africa
-	case "FK":
-		return Americas
-	case "FO":
-		return Europe
-	case "FJ":
-		return Oceania
-	case "FI":
-		return Europe
-	case "FR":
-		return Europe
-	case "GF":
-		return Americas
-	case "PF":
-		return Oceania
-	case "GA":
-		return Africa
-	case "GM":
-		return Africa
-	case "GE":
-		return Asia
-	case "DE":
-		return Europe
-	case "GH":
-		return Africa
-	case "GI":
-		return Europe
-	case "GR":
-		return Europe
-	case "GL":
-		return Americas
-	case "GD":
-		return Americas
-	case "GP":
-		return Americas
-	case "GU":
-		return Oceania
-	case "GT":
-		return Americas
-	case "GN":
-		return Africa
-	case "GW":
-		return Africa
-	case "GY":
-		return Americas
-	case "HT":
-		return Americas
-	case "VA":
-		return Europe
-	case "HN":
-		return Americas
-	case "HU":
-		return Europe
-	case "IS":
-		return Europe
-	case "IN":
-		return Asia
-	case "ID":
-		return Asia
-	case "IR":
-		return Asia
-	case "IQ":
-		return Asia
-	case "IE":
-		return Europe
-	case "IL":
-		return Asia
-	case "IT":
-		return Europe
-	case "JM":
-		return Americas
-	case "JP":
-		return Asia
-	case "JO":
-		return Asia
-	case "KZ":
-		return Asia
-	case "KE":
-		return Africa
-	case "KI":
-		return Oceania
-	case "KP":
-		return Asia
-	case "KR":
-		return Asia
-	case "KW":
-		return Asia
-	case "KG":
-		return Asia
-	case "LA":
-		return Asia
-	case "LV":
-		return Europe
-	case "LB":
-		return Asia
-	case "LS":
-		return Africa
-	case "LR":
-		return Africa
-	case "LY":
-		return Africa
-	case "LI":
-		return Europe
-	case "LT":
-		return Europe
-	case "LU":
-		return Europe
-	case "MK":
-		return Europe
-	case "MG":
-		return Africa
-	case "MW":
-		return Africa
-	case "MY":
-		return Asia
-	case "MV":
-		return Asia
-	case "ML":
-		return Africa
-	case "MT":
-		return Europe
-	case "MH":
-		return Oceania
-	case "MQ":
-		return Americas
-	case "MR":
-		return Africa
-	case "MU":
-		return Africa
-	case "YT":
-		return Africa
-	case "MX":
-		return Americas
-	case "FM":
-		return Oceania
-	case "MD":
-		return Europe
-	case "MC":
-		return Europe
-	case "MN":
-		return Asia
-	case "MS":
-		return Americas
-	case "MA":
-		return Africa
-	case "MZ":
-		return Africa
-	case "NA":
-		return Africa
-	case "NR":
-		return Oceania
-	case "NP":
-		return Asia
-	case "NL":
-		return Europe
-	case "AN":
-		return Americas
-	case "NC":
-		return Oceania
-	case "NZ":
-		return Oceania
-	case "NI":
-		return Americas
-	case "NE":
-		return Africa
-	case "NG":
-		return Africa
-	case "NU":
-		return Oceania
-	case "NF":
-		return Oceania
-	case "MP":
-		return Oceania
-	case "NO":
-		return Europe
-	case "OM":
-		return Asia
-	case "PK":
-		return Asia
-	case "PW":
-		return Oceania
-	case "--":
-		return Asia
-	case "PA":
-		return Americas
-	case "PG":
-		return Oceania
-	case "PY":
-		return Americas
-	case "PE":
-		return Americas
-	case "PH":
-		return Asia
-	case "PN":
-		return Oceania
-	case "PL":
-		return Europe
-	case "PT":
-		return Europe
-	case "PR":
-		return Americas
-	case "QA":
-		return Asia
-	case "RE":
-		return Africa
-	case "RO":
-		return Europe
-	case "RU":
-		return Asia
-	case "RW":
-		return Africa
-	case "KN":
-		return Americas
-	case "LC":
-		return Americas
-	case "PM":
-		return Americas
-	case "VC":
-		return Americas
-	case "SM":
-		return Europe
-	case "ST":
-		return Africa
-	case "SA":
-		return Asia
-	case "SN":
-		return Africa
-	case "SC":
-		return Africa
-	case "SL":
-		return Africa
-	case "SG":
-		return Asia
-	case "SK":
-		return Europe
-	case "SI":
-		return Europe
-	case "SB":
-		return Oceania
-	case "SO":
-		return Africa
-	case "ZA":
-		return Africa
-	case "ES":
-		return Europe
-	case "LK":
-		return Asia
-	case "SD":
-		return Africa
-	case "SR":
-		return Americas
-	case "SJ":
-		return Europe
-	case "SZ":
-		return Africa
-	case "SE":
-		return Europe
-	case "CH":
-		return Europe
-	case "SY":
-		return Asia
-	case "TW":
-		return Asia
-	case "TJ":
-		return Asia
-	case "TZ":
-		return Africa
-	case "TH":
-		return Asia
-	case "TG":
-		return Africa
-	case "TK":
-		return Oceania
-	case "TO":
-		return Oceania
-	case "TT":
-		return Americas
-	case "TN":
-		return Africa
-	case "TR":
-		return Asia
-	case "TM":
-		return Asia
-	case "TC":
-		return Americas
-	case "TV":
-		return Oceania
-	case "UG":
-		return Africa
-	case "UA":
-		return Europe
-	case "AE":
-		return Asia
-	case "GB":
-		return Europe
-	case "GB-ENG":
-		return Europe
-	case "GB-WLS":
-		return Europe
-	case "GB-SCT":
-		return Europe
-	case "GB-NIR":
-		return Europe
-	case "US":
-		return Americas
-	case "UY":
-		return Americas
-	case "UZ":
-		return Asia
-	case "VU":
-		return Oceania
-	case "VE":
-		return Americas
-	case "VN":
-		return Asia
-	case "VI":
-		return Americas
-	case "WF":
-		return Oceania
-	case "EH":
-		return Africa
-	case "WS":
-		return Oceania
-	case "YE":
-		return Asia
-	case "ZR":
-		return Africa
-	case "ZM":
-		return Africa
-	case "ZW":
-		return Africa
-	}
-	return Other
+type gameformation struct {
+	Football struct {
+		Game struct {
+			HomeFormation struct {
+				Formation string `json:"formation"`
+			} `json:"homeFormation"`
+			AwayFormation struct {
+				Formation string `json:"formation"`
+			} `json:"awayFormation"`
+		} `json:"game"`
+	} `json:"football"`
+}
+
+// getFormationShape asks Sorare for the formation the team lined up in, the
+// lineup lengths are only a fallback as substitutions and missing players
+// change them. It is a request of its own so a game still loads without it.
+func getFormationShape(id string, isHome bool) string {
+	q := graphql.NewRequest(`
+		query($slug: ID!) {
+			football {
+				game(id:$slug) {
+					homeFormation {
+						formation
+					}
+					awayFormation {
+						formation
+					}
+				}
+			}
+		}
+	`)
+	q.Var("slug", id)
+	res, err := callSorareApi[gameformation](q)
+	if err != nil {
+		return ""
+	}
+	if isHome {
+		return normalizeShape(res.Football.Game.HomeFormation.Formation)
+	}
+	return normalizeShape(res.Football.Game.AwayFormation.Formation)
+}
+
+// normalizeShape writes "4-2-3-1", "4231" or "FORMATION_4_2_3_1" as
+// "4-2-3-1".
+func normalizeShape(s string) string {
+	var groups []string
+	cur := ""
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			cur += string(r)
+		} else if cur != "" {
+			groups = append(groups, cur)
+			cur = ""
+		}
+	}
+	if cur != "" {
+		groups = append(groups, cur)
+	}
+	if len(groups) == 1 {
+		groups = strings.Split(groups[0], "")
+	}
+	return strings.Join(groups, "-")
+}
+
+func getShape(players [][]compplayers) string {
+	var lines []string
+	for _, l := range players {
+		if len(l) == 1 && l[0].position == "GK" {
+			continue
+		}
+		lines = append(lines, strconv.Itoa(len(l)))
+	}
+	return strings.Join(lines, "-")
 }
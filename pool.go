@@ -0,0 +1,67 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// The classic answer of a day is picked in the pool by the puzzle number.
+// Before the pool is replaced, the answers that may already be played are
+// pinned so a refresh never changes them.
+var pool []playersub
+var pins = make(map[string]string)
+var poolMu sync.RWMutex
+
+func loadPool() {
+	if p, err := pick[[]playersub]("players"); err == nil {
+		pool = p
+	}
+	if p, err := pick[map[string]string]("pins"); err == nil {
+		pins = p
+	}
+}
+
+func getPool() []playersub {
+	poolMu.RLock()
+	defer poolMu.RUnlock()
+	return pool
+}
+
+func getClassicAnswer(day time.Time) string {
+	poolMu.RLock()
+	defer poolMu.RUnlock()
+	return getAnswer(day)
+}
+
+// getAnswer must be called with poolMu held.
+func getAnswer(day time.Time) string {
+	if slug, ok := pins[day.Format("2006-01-02")]; ok {
+		return slug
+	}
+	if len(pool) == 0 {
+		return ""
+	}
+	return pool[getPlayerIndex(day, len(pool))].Slug
+}
+
+// replacePool pins the answers from yesterday to tomorrow, the days players
+// can be on, then swaps the pool.
+func replacePool(fresh []playersub) {
+	poolMu.Lock()
+	defer poolMu.Unlock()
+	today := getToday()
+	for d := -1; d <= 1; d++ {
+		day := today.AddDate(0, 0, d)
+		if slug := getAnswer(day); slug != "" {
+			pins[day.Format("2006-01-02")] = slug
+		}
+	}
+	for key := range pins {
+		if day, err := time.ParseInLocation("2006-01-02", key, loc); err == nil && day.Before(today.AddDate(0, 0, -1)) {
+			delete(pins, key)
+		}
+	}
+	pool = fresh
+	dump("pins", pins)
+	dump("players", fresh)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// Every mode is numbered from the day it went live, the first puzzle being #1.
+// The launches can be changed in the schedule section of the config.
+var puzzleLaunches = map[string]string{
+	"classic":   "2023-04-30",
+	"hard":      "2023-04-30",
+	"comp":      "2023-08-01",
+	"comp-hard": "2023-08-01",
+	"season":    "2023-08-01",
+	"score":     "2023-08-01",
+}
+
+type puzzleinfos struct {
+	Mode       string `json:"mode"`
+	Number     int    `json:"number"`
+	MaxGuesses int    `json:"maxGuesses"`
+	Url        string `json:"url"`
+	Reveal     string `json:"reveal,omitempty"`
+}
+
+type puzzleday struct {
+	Date         string        `json:"date"`
+	Timezone     string        `json:"timezone"`
+	RolloverHour int           `json:"rolloverHour"`
+	PerUserDay   bool          `json:"perUserDay"`
+	NextRollover time.Time     `json:"nextRollover"`
+	SecondsLeft  int           `json:"secondsUntilRollover"`
+	Puzzles      []puzzleinfos `json:"puzzles"`
+}
+
+// Days are counted on the calendar so summer time doesn't shift the numbers.
+func getPuzzleNumber(mode string, day time.Time) int {
+	launch, err := time.Parse("2006-01-02", puzzleLaunches[mode])
+	if err != nil {
+		launch, _ = time.Parse("2006-01-02", puzzleLaunches["classic"])
+	}
+	date := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	return int(date.Sub(launch).Hours()/24) + 1
+}
+
+func getPuzzleDay(now time.Time, reveals map[string]string) puzzleday {
+	day := getPuzzleDate(now)
+	next := getNextRollover(now)
+	ret := puzzleday{
+		Date:         day.Format("2006-01-02"),
+		Timezone:     loc.String(),
+		RolloverHour: settings.Schedule.RolloverHour,
+		PerUserDay:   settings.Schedule.PerUserDay,
+		NextRollover: next,
+		SecondsLeft:  int(next.Sub(now).Seconds()),
+	}
+	for mode := range puzzleLaunches {
+		infos := puzzleinfos{
+			Mode:       mode,
+			Number:     getPuzzleNumber(mode, day),
+			MaxGuesses: maxGuesses[mode],
+			Url:        getModeUrl(mode),
+		}
+		infos.Reveal = reveals[mode]
+		ret.Puzzles = append(ret.Puzzles, infos)
+	}
+	sort.Slice(ret.Puzzles, func(i, j int) bool { return ret.Puzzles[i].Mode < ret.Puzzles[j].Mode })
+	return ret
+}
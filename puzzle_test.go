@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// The numbers go up by one every day, summer time or not.
+func TestGetPuzzleNumberAcrossDST(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip("no timezone data", err)
+	}
+	loc = paris
+	tests := []struct {
+		mode string
+		day  time.Time
+		want int
+	}{
+		{"classic", time.Date(2023, 4, 30, 0, 0, 0, 0, paris), 1},
+		{"classic", time.Date(2023, 5, 1, 0, 0, 0, 0, paris), 2},
+		{"comp", time.Date(2023, 8, 1, 0, 0, 0, 0, paris), 1},
+		// Summer time starts on the night of March 29th 2026.
+		{"classic", time.Date(2026, 3, 28, 0, 0, 0, 0, paris), 1064},
+		{"classic", time.Date(2026, 3, 29, 0, 0, 0, 0, paris), 1065},
+		{"classic", time.Date(2026, 3, 30, 0, 0, 0, 0, paris), 1066},
+		// And ends on the night of October 25th 2026.
+		{"comp", time.Date(2026, 10, 24, 0, 0, 0, 0, paris), 1181},
+		{"comp", time.Date(2026, 10, 25, 0, 0, 0, 0, paris), 1182},
+		{"comp", time.Date(2026, 10, 26, 0, 0, 0, 0, paris), 1183},
+		// A late evening in Paris is still the same day.
+		{"classic", time.Date(2026, 10, 25, 23, 30, 0, 0, paris), 1275},
+		{"classic", getPuzzleDate(time.Date(2026, 10, 25, 23, 30, 0, 0, time.UTC)), 1276},
+	}
+	for _, tt := range tests {
+		if got := getPuzzleNumber(tt.mode, tt.day); got != tt.want {
+			t.Errorf("%s on %s is #%d, want #%d", tt.mode, tt.day, got, tt.want)
+		}
+	}
+}
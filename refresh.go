@@ -0,0 +1,165 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// A refresh records what changed in the clubs or the player pool, the admin
+// page shows the last ones.
+type refresh struct {
+	Kind    string
+	At      time.Time
+	Count   int
+	Added   []string
+	Removed []string
+	Error   string
+}
+
+var refreshes []refresh
+var refreshesMu sync.Mutex
+
+// refreshMu keeps a manual refresh from running along a scheduled one.
+var refreshMu sync.Mutex
+
+// The kinds are named after the files they are saved in.
+var refreshKinds = []string{"clubs", "players"}
+
+const keptRefreshes = 20
+
+func loadRefreshes() {
+	if r, err := pick[[]refresh]("refreshes"); err == nil {
+		refreshes = r
+	}
+}
+
+func recordRefresh(r refresh) {
+	refreshesMu.Lock()
+	defer refreshesMu.Unlock()
+	refreshes = append(refreshes, r)
+	if len(refreshes) > keptRefreshes {
+		refreshes = refreshes[len(refreshes)-keptRefreshes:]
+	}
+	dump("refreshes", refreshes)
+}
+
+// getRefreshes gives the refreshes from the latest, and the last successful
+// one of every kind.
+func getRefreshes() ([]refresh, map[string]refresh) {
+	refreshesMu.Lock()
+	defer refreshesMu.Unlock()
+	last := make(map[string]refresh)
+	var ret []refresh
+	for i := len(refreshes) - 1; i >= 0; i-- {
+		r := refreshes[i]
+		ret = append(ret, r)
+		if _, ok := last[r.Kind]; !ok && r.Error == "" {
+			last[r.Kind] = r
+		}
+	}
+	return ret, last
+}
+
+// isDue counts from the last refresh, or from when the file was last written
+// before there was any, so a first start keeps the saved clubs and players.
+func isDue(kind string, every int) bool {
+	_, last := getRefreshes()
+	at := last[kind].At
+	if at.IsZero() {
+		info, err := os.Stat(filepath.Join(settings.Data.Dir, kind+".bin"))
+		if err != nil {
+			return true
+		}
+		at = info.ModTime()
+	}
+	return time.Since(at) >= time.Duration(every)*24*time.Hour
+}
+
+// scheduleRefreshes checks every hour if the clubs or the player pool are
+// due, a failed refresh is tried again the next hour or in 5 minutes when
+// there are no clubs at all.
+func scheduleRefreshes() {
+	for {
+		if isDue("clubs", settings.Refresh.ClubsEvery) {
+			runRefresh("clubs")
+		}
+		if isDue("players", settings.Refresh.PlayersEvery) {
+			runRefresh("players")
+		}
+		if len(getClubs()) == 0 {
+			time.Sleep(5 * time.Minute)
+		} else {
+			time.Sleep(time.Hour)
+		}
+	}
+}
+
+func runRefresh(kind string) bool {
+	refreshMu.Lock()
+	defer refreshMu.Unlock()
+	switch kind {
+	case "clubs":
+		return refreshClubs()
+	case "players":
+		return refreshPlayers()
+	}
+	return false
+}
+
+// refreshPlayers keeps the pool when any club can't be fetched, its players
+// would be missing from the new one.
+func refreshPlayers() bool {
+	old := getPool()
+	size := settings.Refresh.PoolSize
+	if size == 0 {
+		size = len(old)
+	}
+	if size == 0 {
+		recordRefresh(refresh{Kind: "players", At: time.Now(), Error: "no pool to take the size from, set refresh.pool_size"})
+		return false
+	}
+	fresh, err := getNMostSubscribedPlayers(size)
+	if err != nil {
+		log.Println("Couldn't refresh the players, keeping the last ones", err)
+		recordRefresh(refresh{Kind: "players", At: time.Now(), Count: len(old), Error: err.Error()})
+		return false
+	}
+	replacePool(fresh)
+	var before, after []string
+	for _, p := range old {
+		before = append(before, p.DisplayName)
+	}
+	for _, p := range fresh {
+		after = append(after, p.DisplayName)
+	}
+	added, removed := diff(before, after)
+	recordRefresh(refresh{Kind: "players", At: time.Now(), Count: len(fresh), Added: added, Removed: removed})
+	return true
+}
+
+func diff(before, after []string) ([]string, []string) {
+	was := make(map[string]bool)
+	for _, s := range before {
+		was[s] = true
+	}
+	is := make(map[string]bool)
+	var added, removed []string
+	for _, s := range after {
+		is[s] = true
+		if !was[s] {
+			added = append(added, s)
+		}
+	}
+	for _, s := range before {
+		if !is[s] {
+			removed = append(removed, s)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
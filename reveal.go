@@ -0,0 +1,146 @@
+package main
+
+import (
+	"math/rand"
+	"time"
+)
+
+type visibility struct {
+	flag  bool
+	score bool
+}
+
+// A reveal strategy decides which flags and scores of the formation are
+// shown after a number of wrong guesses. The seed is the same for everyone
+// on a given day. Steps is the number of misses before everything is shown.
+type revealStrategy struct {
+	rule   string
+	reveal func(players [][]compplayers, misses int, seed int64) [][]visibility
+	steps  func(players [][]compplayers) int
+}
+
+var revealStrategies = map[string]revealStrategy{
+	"flat": {"If you don't find the team, the nationality of one of the player is revealed", func(players [][]compplayers, misses int, seed int64) [][]visibility {
+		return revealInOrder(players, flatOrder(players), misses, false)
+	}, countPlayers},
+	"random": {"If you don't find the team, the nationality of a random player is revealed", func(players [][]compplayers, misses int, seed int64) [][]visibility {
+		order := flatOrder(players)
+		r := rand.New(rand.NewSource(seed))
+		r.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+		return revealInOrder(players, order, misses, false)
+	}, countPlayers},
+	"lines": {"If you don't find the team, the nationalities of a whole line are revealed, starting with the defence", func(players [][]compplayers, misses int, seed int64) [][]visibility {
+		ret := newVisibility(players, false, true)
+		// The goalkeeper line comes last.
+		for n := 0; n < misses && n < len(players); n++ {
+			l := (n + 1) % len(players)
+			for i := range ret[l] {
+				ret[l][i].flag = true
+			}
+		}
+		return ret
+	}, countLines},
+	"scores": {"Every nationality is shown, if you don't find the team the score of one of the player is revealed", func(players [][]compplayers, misses int, seed int64) [][]visibility {
+		return revealInOrder(players, flatOrder(players), misses, true)
+	}, countPlayers},
+}
+
+var revealSchedule = map[time.Weekday]string{
+	time.Monday:    "flat",
+	time.Tuesday:   "lines",
+	time.Wednesday: "random",
+	time.Thursday:  "scores",
+	time.Friday:    "flat",
+	time.Saturday:  "random",
+	time.Sunday:    "lines",
+}
+
+func getRevealStrategy(name string) revealStrategy {
+	if s, ok := revealStrategies[name]; ok {
+		return s
+	}
+	return revealStrategies["flat"]
+}
+
+func newVisibility(players [][]compplayers, flag, score bool) [][]visibility {
+	ret := make([][]visibility, len(players))
+	for l, line := range players {
+		ret[l] = make([]visibility, len(line))
+		for i := range line {
+			ret[l][i] = visibility{flag: flag, score: score}
+		}
+	}
+	return ret
+}
+
+func countPlayers(players [][]compplayers) int {
+	return len(flatOrder(players))
+}
+
+func countLines(players [][]compplayers) int {
+	return len(players)
+}
+
+func flatOrder(players [][]compplayers) [][2]int {
+	var ret [][2]int
+	for l, line := range players {
+		for i := range line {
+			ret = append(ret, [2]int{l, i})
+		}
+	}
+	return ret
+}
+
+func revealInOrder(players [][]compplayers, order [][2]int, misses int, scores bool) [][]visibility {
+	ret := newVisibility(players, scores, !scores)
+	for n := 0; n < misses && n < len(order); n++ {
+		if scores {
+			ret[order[n][0]][order[n][1]].score = true
+		} else {
+			ret[order[n][0]][order[n][1]].flag = true
+		}
+	}
+	return ret
+}
+
+// getCompVisibility runs the day's strategy on every lineup on its own and
+// hands the misses out to them in turn, skipping the ones fully shown. The
+// bench of the hard variant is a last line of the first lineup so it's
+// hidden like the starters.
+func getCompVisibility(mode string, games []formation, misses int) ([][][]visibility, []visibility) {
+	strategy := getRevealStrategy(games[0].reveal)
+	withBench := compModes[mode].bench && len(games[0].bench) > 0
+	lineups := make([][][]compplayers, len(games))
+	for k, f := range games {
+		lineups[k] = append([][]compplayers{}, f.players...)
+	}
+	if withBench {
+		lineups[0] = append(lineups[0], games[0].bench)
+	}
+	counts := make([]int, len(games))
+	next := 0
+	for n := 0; n < misses; n++ {
+		given := false
+		for tries := 0; tries < len(games) && !given; tries++ {
+			k := next
+			next = (next + 1) % len(games)
+			if counts[k] < strategy.steps(lineups[k]) {
+				counts[k]++
+				given = true
+			}
+		}
+		if !given {
+			break
+		}
+	}
+	ret := make([][][]visibility, len(games))
+	var bench []visibility
+	for k := range games {
+		ret[k] = strategy.reveal(lineups[k], counts[k], games[0].seed+int64(k))
+	}
+	if withBench {
+		bench = ret[0][len(ret[0])-1]
+		ret[0] = ret[0][:len(ret[0])-1]
+	}
+	return ret, bench
+}
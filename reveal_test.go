@@ -0,0 +1,164 @@
+package main
+
+import "testing"
+
+func newLineup(shape ...int) [][]compplayers {
+	var ret [][]compplayers
+	for _, n := range shape {
+		ret = append(ret, make([]compplayers, n))
+	}
+	return ret
+}
+
+// countShown gives how many flags and scores are shown.
+func countShown(visible [][]visibility) (int, int) {
+	flags, scores := 0, 0
+	for _, line := range visible {
+		for _, v := range line {
+			if v.flag {
+				flags++
+			}
+			if v.score {
+				scores++
+			}
+		}
+	}
+	return flags, scores
+}
+
+func TestRevealStrategies(t *testing.T) {
+	players := newLineup(1, 4, 3, 3)
+	tests := []struct {
+		strategy string
+		misses   int
+		flags    int
+		scores   int
+	}{
+		{"flat", 0, 0, 11},
+		{"flat", 1, 1, 11},
+		{"flat", 5, 5, 11},
+		{"flat", 20, 11, 11},
+		{"random", 0, 0, 11},
+		{"random", 3, 3, 11},
+		{"random", 20, 11, 11},
+		{"lines", 0, 0, 11},
+		{"lines", 1, 4, 11},
+		{"lines", 3, 10, 11},
+		{"lines", 4, 11, 11},
+		{"scores", 0, 11, 0},
+		{"scores", 2, 11, 2},
+		{"scores", 20, 11, 11},
+	}
+	for _, tt := range tests {
+		visible := getRevealStrategy(tt.strategy).reveal(players, tt.misses, 42)
+		if flags, scores := countShown(visible); flags != tt.flags || scores != tt.scores {
+			t.Errorf("%s after %d misses shows %d flags and %d scores, want %d and %d", tt.strategy, tt.misses, flags, scores, tt.flags, tt.scores)
+		}
+	}
+}
+
+func TestRevealStrategySteps(t *testing.T) {
+	players := newLineup(1, 4, 4, 2)
+	for name, s := range revealStrategies {
+		steps := s.steps(players)
+		before := s.reveal(players, steps-1, 1)
+		after := s.reveal(players, steps, 1)
+		more := s.reveal(players, steps+1, 1)
+		f1, s1 := countShown(before)
+		f2, s2 := countShown(after)
+		f3, s3 := countShown(more)
+		if f1+s1 >= f2+s2 || f2 != f3 || s2 != s3 {
+			t.Errorf("%s isn't fully shown after exactly %d misses", name, steps)
+		}
+	}
+}
+
+// The lines strategy keeps the goalkeeper for last.
+func TestRevealLinesEndsWithTheGoalkeeper(t *testing.T) {
+	players := newLineup(1, 4, 3, 3)
+	visible := getRevealStrategy("lines").reveal(players, 3, 0)
+	if visible[0][0].flag {
+		t.Error("the goalkeeper is shown before the other lines")
+	}
+}
+
+func TestRevealRandomIsTheSameForTheDay(t *testing.T) {
+	players := newLineup(1, 4, 3, 3)
+	s := getRevealStrategy("random")
+	a := s.reveal(players, 4, 7)
+	b := s.reveal(players, 4, 7)
+	for l := range a {
+		for i := range a[l] {
+			if a[l][i] != b[l][i] {
+				t.Fatal("the same seed reveals different players")
+			}
+		}
+	}
+}
+
+func newSeasonGames(reveal string) []formation {
+	games := []formation{
+		{players: newLineup(1, 4, 3, 3), bench: make([]compplayers, 3)},
+		{players: newLineup(1, 4, 4, 2)},
+		{players: newLineup(1, 3, 5, 2)},
+	}
+	for i := range games {
+		games[i].reveal = reveal
+	}
+	return games
+}
+
+func TestGetCompVisibility(t *testing.T) {
+	tests := []struct {
+		reveal string
+		misses int
+		// flags shown on every lineup
+		flags []int
+	}{
+		{"flat", 0, []int{0, 0, 0}},
+		{"flat", 3, []int{1, 1, 1}},
+		{"flat", 4, []int{2, 1, 1}},
+		{"lines", 3, []int{4, 4, 3}},
+		// The fourth miss goes to the first lineup, not to the goalkeeper of
+		// the second one.
+		{"lines", 4, []int{7, 4, 3}},
+		{"lines", 12, []int{11, 11, 11}},
+		{"lines", 20, []int{11, 11, 11}},
+	}
+	for _, tt := range tests {
+		visible, bench := getCompVisibility("season", newSeasonGames(tt.reveal), tt.misses)
+		if bench != nil {
+			t.Errorf("%s: the season mode has no bench", tt.reveal)
+		}
+		for k, want := range tt.flags {
+			if flags, _ := countShown(visible[k]); flags != want {
+				t.Errorf("%s after %d misses shows %d flags on lineup %d, want %d", tt.reveal, tt.misses, flags, k, want)
+			}
+		}
+	}
+}
+
+// The bench of the hard variant is hidden like the starters and revealed
+// after them.
+func TestGetCompVisibilityBench(t *testing.T) {
+	games := newSeasonGames("flat")[:1]
+	tests := []struct {
+		reveal string
+		misses int
+		flags  int
+		scores int
+	}{
+		{"flat", 0, 0, 3},
+		{"flat", 11, 0, 3},
+		{"flat", 12, 1, 3},
+		{"scores", 0, 3, 0},
+		{"scores", 14, 3, 3},
+	}
+	for _, tt := range tests {
+		games[0].reveal = tt.reveal
+		_, bench := getCompVisibility("comp-hard", games, tt.misses)
+		if flags, scores := countShown([][]visibility{bench}); flags != tt.flags || scores != tt.scores {
+			t.Errorf("%s after %d misses shows %d flags and %d scores on the bench, want %d and %d", tt.reveal, tt.misses, flags, scores, tt.flags, tt.scores)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"html"
+	"sync"
+	"time"
+)
+
+// Rooms are only kept in memory and dropped after roomTTL without activity.
+var roomTTL = 2 * time.Hour
+
+type racer struct {
+	nickname string
+	rows     [][]color
+	won      bool
+}
+
+type room struct {
+	code        string
+	slug        string
+	expires     time.Time
+	racers      map[string]*racer
+	order       []string
+	winner      string
+	subscribers map[chan string]bool
+}
+
+var rooms = make(map[string]*room)
+var roomsMu sync.Mutex
+
+const roomAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+func newRoomCode() string {
+	b := make([]byte, 5)
+	rand.Read(b)
+	for i := range b {
+		b[i] = roomAlphabet[int(b[i])%len(roomAlphabet)]
+	}
+	return string(b)
+}
+
+func createRoom(slug string) *room {
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+	code := newRoomCode()
+	for rooms[code] != nil {
+		code = newRoomCode()
+	}
+	r := &room{
+		code:        code,
+		slug:        slug,
+		expires:     time.Now().Add(roomTTL),
+		racers:      make(map[string]*racer),
+		subscribers: make(map[chan string]bool),
+	}
+	rooms[code] = r
+	return r
+}
+
+func getRoom(code string) (*room, bool) {
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+	r, ok := rooms[code]
+	if !ok || time.Now().After(r.expires) {
+		return nil, false
+	}
+	return r, true
+}
+
+func (r *room) join(token, nickname string) {
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+	r.expires = time.Now().Add(roomTTL)
+	if existing, ok := r.racers[token]; ok {
+		existing.nickname = nickname
+	} else {
+		r.racers[token] = &racer{nickname: nickname}
+		r.order = append(r.order, token)
+	}
+	r.broadcast()
+}
+
+func (r *room) isRacer(token string) bool {
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+	_, ok := r.racers[token]
+	return ok
+}
+
+func (r *room) addRow(token string, cells []cell, won bool) {
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+	rc, ok := r.racers[token]
+	if !ok || rc.won {
+		return
+	}
+	r.expires = time.Now().Add(roomTTL)
+	rc.rows = append(rc.rows, getColors(cells))
+	if won {
+		rc.won = true
+		if r.winner == "" {
+			r.winner = rc.nickname
+		}
+	}
+	r.broadcast()
+}
+
+func (r *room) subscribe() chan string {
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+	ch := make(chan string, 8)
+	r.subscribers[ch] = true
+	ch <- r.scoreboard()
+	return ch
+}
+
+func (r *room) unsubscribe(ch chan string) {
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+	delete(r.subscribers, ch)
+}
+
+// broadcast must be called with roomsMu held. Slow subscribers miss updates
+// rather than blocking the room, the next one carries the whole board anyway.
+func (r *room) broadcast() {
+	board := r.scoreboard()
+	for ch := range r.subscribers {
+		select {
+		case ch <- board:
+		default:
+		}
+	}
+}
+
+func (r *room) scoreboard() string {
+	var ret bytes.Buffer
+	if r.winner != "" {
+		ret.WriteString(`<h2>` + html.EscapeString(r.winner) + ` won the race !</h2>`)
+	}
+	ret.WriteString(`<div class="racers">`)
+	for _, token := range r.order {
+		rc := r.racers[token]
+		ret.WriteString(`<div class="racer">`)
+		ret.WriteString(fmt.Sprintf(`<h3>%s (%d)</h3>`, html.EscapeString(rc.nickname), len(rc.rows)))
+		for _, row := range rc.rows {
+			ret.WriteString(`<div class="mini-row">`)
+			for _, c := range row {
+				ret.WriteString(`<div class="mini ` + string(c) + `"></div>`)
+			}
+			ret.WriteString(`</div>`)
+		}
+		ret.WriteString(`</div>`)
+	}
+	ret.WriteString(`</div>`)
+	return ret.String()
+}
+
+// closeRooms ends every board stream so the server can shut down without
+// waiting for the races to be over.
+func closeRooms() {
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+	for _, r := range rooms {
+		for ch := range r.subscribers {
+			close(ch)
+		}
+		r.subscribers = make(map[chan string]bool)
+	}
+}
+
+func expireRooms() {
+	for range time.Tick(10 * time.Minute) {
+		roomsMu.Lock()
+		for code, r := range rooms {
+			if time.Now().After(r.expires) {
+				for ch := range r.subscribers {
+					close(ch)
+				}
+				r.subscribers = make(map[chan string]bool)
+				delete(rooms, code)
+			}
+		}
+		roomsMu.Unlock()
+	}
+}
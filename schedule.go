@@ -0,0 +1,47 @@
+package main
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The puzzles change every day at the rollover hour of the timezone set in
+// the config. With per_user_day the classic puzzle follows the date of the
+// player instead, like Wordle. Composition and score puzzles are fetched from
+// Sorare once a day so they always follow the server.
+var loc *time.Location
+
+// getPuzzleDate gives the day of the puzzle played at t, at midnight.
+func getPuzzleDate(t time.Time) time.Time {
+	t = t.In(loc).Add(-time.Duration(settings.Schedule.RolloverHour) * time.Hour)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+func getToday() time.Time {
+	return getPuzzleDate(time.Now())
+}
+
+func getNextRollover(t time.Time) time.Time {
+	day := getPuzzleDate(t)
+	return time.Date(day.Year(), day.Month(), day.Day()+1, settings.Schedule.RolloverHour, 0, 0, 0, loc)
+}
+
+func getPlayerIndex(day time.Time, n int) int {
+	return (getPuzzleNumber("classic", day) - 1) % n
+}
+
+// getClientDate reads the date of the player. No timezone is more than a day
+// away from UTC so anything further is refused.
+func getClientDate(c *gin.Context) (time.Time, bool) {
+	date, err := time.Parse("2006-01-02", c.Query("date"))
+	if err != nil {
+		return time.Time{}, false
+	}
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if diff := date.Sub(today); diff < -24*time.Hour || diff > 24*time.Hour {
+		return time.Time{}, false
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc), true
+}
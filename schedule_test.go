@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The players are at most a day away from UTC.
+func TestGetClientDate(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip("no timezone data", err)
+	}
+	loc = paris
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		date string
+		ok   bool
+	}{
+		{today.Format("2006-01-02"), true},
+		{today.AddDate(0, 0, -1).Format("2006-01-02"), true},
+		{today.AddDate(0, 0, 1).Format("2006-01-02"), true},
+		{today.AddDate(0, 0, -2).Format("2006-01-02"), false},
+		{today.AddDate(0, 0, 2).Format("2006-01-02"), false},
+		{"", false},
+		{"yesterday", false},
+		{today.Format("02/01/2006"), false},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/?date="+tt.date, nil)
+		day, ok := getClientDate(c)
+		if ok != tt.ok {
+			t.Errorf("%q is accepted: %v, want %v", tt.date, ok, tt.ok)
+			continue
+		}
+		if ok && (day.Format("2006-01-02") != tt.date || day.Location() != paris || day.Hour() != 0) {
+			t.Errorf("%q gives %s, want its midnight in %s", tt.date, day, paris)
+		}
+	}
+}
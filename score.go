@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+)
+
+// getScoreGame picks a game of the last gameweek and keeps both sides, the
+// home team first. The clubs of the day's other puzzles are left out so the
+// lineups don't give them away.
+func getScoreGame(picked map[string]bool) ([]formation, error) {
+	slug := getLastGameWeek(gamePolicy.fixtureOffset)
+	var gamesId []string
+	for _, c := range getGamesFromGameweek(slug) {
+		if gamePolicy.reject(c, picked) == "" {
+			gamesId = append(gamesId, c.id)
+		}
+	}
+	if len(gamesId) == 0 {
+		return nil, fmt.Errorf("no game of %s without the day's clubs passes the selection policy", slug)
+	}
+	gameId := gamesId[rand.Intn(len(gamesId))]
+	return []formation{getGameInfos(gameId, true), getGameInfos(gameId, false)}, nil
+}
+
+func getTopScore(games []formation) float32 {
+	var top float32
+	for _, f := range games {
+		for _, line := range f.players {
+			for _, p := range line {
+				if p.score > top {
+					top = p.score
+				}
+			}
+		}
+	}
+	return top
+}
+
+func getScorePick(games []formation, pick string) (compplayers, bool) {
+	var t, l, i int
+	if _, err := fmt.Sscanf(pick, "%d-%d-%d", &t, &l, &i); err != nil {
+		return compplayers{}, false
+	}
+	if t < 0 || t >= len(games) || l < 0 || l >= len(games[t].players) || i < 0 || i >= len(games[t].players[l]) {
+		return compplayers{}, false
+	}
+	return games[t].players[l][i], true
+}
+
+// A top scorer found on the first pick is worth as many points as there are
+// picks, each wrong pick costs one.
+func getScorePoints(g game) int {
+	if !g.won {
+		return 0
+	}
+	return maxGuesses["score"] - g.guesses + 1
+}
+
+func buildScoreField(g game, games []formation) bytes.Buffer {
+	var ret bytes.Buffer
+	over := g.won || g.lost
+	top := getTopScore(games)
+	ret.WriteString(`<div class="fields">`)
+	for t, f := range games {
+		ret.WriteString(`<div class="gameweek">`)
+		ret.WriteString(fmt.Sprintf(`<h3><img src="%s"/> %s</h3>`, f.pictureUrl, f.name))
+		ret.WriteString(`<div class="field">`)
+		for l, line := range f.players {
+			ret.WriteString(`<div class="row">`)
+			for i, player := range line {
+				id := fmt.Sprintf("%d-%d-%d", t, l, i)
+				picked := contains(g.history, id)
+				if over && player.score == top {
+					ret.WriteString(`<div class="player top">`)
+				} else if over || picked {
+					ret.WriteString(`<div class="player">`)
+				} else {
+					ret.WriteString(`<div class="player pick" hx-post="/score-guess?pick=` + id + `" hx-target="#results">`)
+				}
+				ret.WriteString(`<img src="` + player.countryUrl + `">`)
+				if over || picked {
+					ret.WriteString(`<div class="score" style="background-color:` + getColorOfNote(player.score) + `">` + fmt.Sprintf("%v", player.score) + `</div>`)
+				} else {
+					ret.WriteString(`<div class="score hidden-score">?</div>`)
+				}
+				ret.WriteString(`<div class="position">` + player.position + `</div>`)
+				ret.WriteString(`</div>`)
+			}
+			ret.WriteString(`</div>`)
+		}
+		ret.WriteString(`</div></div>`)
+	}
+	ret.WriteString(`</div>`)
+	if g.won {
+		ret.WriteString(fmt.Sprintf(`
+			<div class="winner" id="winner">
+				<h2>Good Job ! You found the top scorer in %d trys and get <span>%d points</span> ! <a href="/comp">Now try the composition version !</a>
+			</div>
+		`, g.guesses, getScorePoints(g)))
+	} else if g.lost {
+		ret.WriteString(fmt.Sprintf(`
+			<div class="loser" id="loser">
+				<h2>The top score was <span>%v</span> ! <a href="/comp">Now try the composition version !</a>
+			</div>
+		`, top))
+	}
+	return ret
+}
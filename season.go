@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// getSeasonGames picks a club from the last gameweek, then looks for its
+// games in the gameweeks before. The most recent game comes first.
+func getSeasonGames(day time.Time, n int, recent map[string]bool) ([]formation, error) {
+	latest, err := getRandomGameFromLastGameweek(day, recent)
+	if err != nil {
+		return nil, err
+	}
+	ret := []formation{latest}
+	for i, slug := range getLastGameWeeks(gamePolicy.fixtureOffset+1, n-1) {
+		if f, ok := getClubGameFromGameweek(slug, latest.slug); ok {
+			f.gameweek = i + 1
+			ret = append(ret, f)
+		}
+	}
+	return ret, nil
+}
+
+func getClubGameFromGameweek(slug, club string) (formation, bool) {
+	for _, c := range getGamesFromGameweek(slug) {
+		if (c.home == club || c.away == club) && c.coverage == gamePolicy.coverage {
+			return getGameInfos(c.id, c.home == club), true
+		}
+	}
+	return formation{}, false
+}
+
+func getGameweekLabel(n int) string {
+	if n == 0 {
+		return "Last gameweek"
+	}
+	return fmt.Sprintf("%d gameweeks ago", n+1)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+type selectionPolicy struct {
+	fixtureOffset    int
+	coverage         string
+	minSubscriptions int
+	leagues          []string
+	excludeDays      int
+}
+
+var gamePolicy = selectionPolicy{
+	fixtureOffset:    2,
+	coverage:         "FULL",
+	minSubscriptions: 1000,
+	excludeDays:      7,
+}
+
+type candidate struct {
+	id       string
+	coverage string
+	league   string
+	home     string
+	away     string
+	homeSubs int
+	awaySubs int
+}
+
+// reject returns why the game can't be picked, or an empty string.
+func (p selectionPolicy) reject(c candidate, recent map[string]bool) string {
+	if c.coverage != p.coverage {
+		return "coverage " + c.coverage
+	}
+	if c.homeSubs <= p.minSubscriptions || c.awaySubs <= p.minSubscriptions {
+		return "not enough subscriptions"
+	}
+	if len(p.leagues) > 0 && !contains(p.leagues, c.league) {
+		return "league " + c.league
+	}
+	if recent[c.home] || recent[c.away] {
+		return "club used recently"
+	}
+	return ""
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+type usedclub struct {
+	Slug string
+	Day  time.Time
+}
+
+func getRecentClubs(day time.Time, days int) map[string]bool {
+	used, _ := pick[[]usedclub]("recent")
+	ret := make(map[string]bool)
+	for _, u := range used {
+		if day.Sub(u.Day) < time.Duration(days)*24*time.Hour {
+			ret[u.Slug] = true
+		}
+	}
+	return ret
+}
+
+// rememberClubs is called once the day's puzzles are stored, so a failed
+// refresh doesn't use up clubs.
+func rememberClubs(day time.Time, slugs ...string) {
+	used, _ := pick[[]usedclub]("recent")
+	var kept []usedclub
+	for _, u := range used {
+		if day.Sub(u.Day) < time.Duration(gamePolicy.excludeDays)*24*time.Hour {
+			kept = append(kept, u)
+		}
+	}
+	for _, slug := range slugs {
+		kept = append(kept, usedclub{Slug: slug, Day: day})
+	}
+	dump("recent", kept)
+}
+
+func runCommand(args []string) {
+	switch args[0] {
+	case "candidates":
+		slug := getLastGameWeek(gamePolicy.fixtureOffset)
+		if len(args) > 1 {
+			slug = args[1]
+		}
+		recent := getRecentClubs(time.Now(), gamePolicy.excludeDays)
+		fmt.Println("Gameweek " + slug)
+		for _, c := range getGamesFromGameweek(slug) {
+			status := "ok"
+			if reason := gamePolicy.reject(c, recent); reason != "" {
+				status = "rejected: " + reason
+			}
+			fmt.Printf("%s\t%s\t%s (%d) - %s (%d)\t%s\n", c.id, c.league, c.home, c.homeSubs, c.away, c.awaySubs, status)
+		}
+	case "config":
+		if len(args) < 2 || args[1] != "print" {
+			log.Fatal("Usage: sordle config print")
+		}
+		printConfig()
+	default:
+		log.Fatal("Unknown command " + args[0])
+	}
+}
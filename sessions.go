@@ -0,0 +1,120 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxGuesses can be changed in the game section of the config.
+var maxGuesses = map[string]int{
+	"classic":   10,
+	"hard":      12,
+	"comp":      12,
+	"comp-hard": 12,
+	"season":    12,
+	"score":     3,
+}
+
+// The hard composition variant also shows the substitutes who came on, the
+// season one shows the lineups of the hidden club over several gameweeks.
+var compModes = map[string]struct {
+	bench     bool
+	gameweeks int
+}{
+	"comp":      {bench: false, gameweeks: 1},
+	"comp-hard": {bench: true, gameweeks: 1},
+	"season":    {bench: false, gameweeks: 3},
+}
+
+type game struct {
+	puzzle  string
+	guesses int
+	hints   int
+	won     bool
+	lost    bool
+	history []string
+	rows    [][]color
+	started time.Time
+}
+
+var sessions = make(map[string]map[string]*game)
+var sessionsMu sync.Mutex
+
+func getSessionToken(c *gin.Context) string {
+	if token := c.GetString("sordle"); token != "" {
+		return token
+	}
+	cookie, err := c.Cookie("sordle")
+	if err == nil && cookie != "" {
+		if token, ok := resolveSession(cookie); ok {
+			c.Set("sordle", token)
+			return token
+		}
+	}
+	token := newSessionToken()
+	setSessionToken(c, token)
+	return token
+}
+
+// revokeLogin ends the login the browser holds, if any, before it is given
+// another token.
+func revokeLogin(c *gin.Context) {
+	if cookie, err := c.Cookie("sordle"); err == nil {
+		logout(cookie)
+	}
+}
+
+func newSessionToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func setSessionToken(c *gin.Context, token string) {
+	c.Set("sordle", token)
+	c.SetCookie("sordle", token, 365*24*60*60, "/", "", false, true)
+}
+
+// withGame gives f the session's game for the mode, starting a new one
+// whenever the puzzle of the day has changed.
+func withGame(c *gin.Context, mode, puzzle string, f func(g *game)) {
+	token := getSessionToken(c)
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	games, ok := sessions[token]
+	if !ok {
+		games = make(map[string]*game)
+		sessions[token] = games
+	}
+	g, ok := games[mode]
+	if !ok || g.puzzle != puzzle {
+		g = &game{puzzle: puzzle, started: time.Now()}
+		games[mode] = g
+	}
+	f(g)
+}
+
+// expireSessions drops the games of past days, players can still be on
+// yesterday's puzzle with their own date. The expired logins go with them.
+func expireSessions() {
+	for range time.Tick(time.Hour) {
+		expireLogins()
+		cutoff := getToday().AddDate(0, 0, -1)
+		sessionsMu.Lock()
+		for token, games := range sessions {
+			for mode, g := range games {
+				if g.started.Before(cutoff) {
+					delete(games, mode)
+				}
+			}
+			if len(games) == 0 {
+				delete(sessions, token)
+			}
+		}
+		sessionsMu.Unlock()
+	}
+}
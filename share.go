@@ -0,0 +1,263 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// share is what a client needs to share a finished game, the text is built
+// here so every client shares the same thing.
+type share struct {
+	ID       string    `json:"id"`
+	Key      string    `json:"-"`
+	Mode     string    `json:"mode"`
+	Puzzle   int       `json:"puzzle"`
+	Tries    int       `json:"tries"`
+	MaxTries int       `json:"maxTries"`
+	Hints    int       `json:"hints"`
+	Won      bool      `json:"won"`
+	Grid     [][]color `json:"grid"`
+	Revealed int       `json:"revealed,omitempty"`
+	Total    int       `json:"total,omitempty"`
+	Points   int       `json:"points,omitempty"`
+	Text     string    `json:"text"`
+	Saved    time.Time `json:"-"`
+}
+
+var shareTitles = map[string]string{
+	"classic":   "#Sordle",
+	"hard":      "#Sordle Hard",
+	"comp":      "#Sordle Composition",
+	"comp-hard": "#Sordle Composition Hard",
+	"season":    "#Sordle Composition Season",
+	"score":     "#Sordle Top Score",
+}
+
+var shareEmojis = map[color]string{
+	GREEN:  "🟩",
+	YELLOW: "🟧",
+	RED:    "🟥",
+}
+
+// A shared game keeps its link for a month.
+const shareTTL = 30 * 24 * time.Hour
+
+var shares = make(map[string]share)
+var shareIds = make(map[string]string)
+var sharesChanged bool
+var sharesMu sync.Mutex
+
+func loadShares() {
+	if s, err := pick[map[string]share]("shares"); err == nil {
+		shares = s
+		for id, s := range shares {
+			shareIds[s.Key] = id
+		}
+		// Shares saved before they expired are dated on the next save.
+		sharesChanged = true
+	}
+}
+
+// saveShare keeps the share so its card can be linked, a game shared twice
+// keeps the same id. The key identifies the session's game.
+func saveShare(key string, s share) share {
+	sharesMu.Lock()
+	defer sharesMu.Unlock()
+	s.Key = key
+	id, ok := shareIds[key]
+	if !ok {
+		id = newRoomCode()
+		for shares[id].Key != "" {
+			id = newRoomCode()
+		}
+	}
+	s.ID = id
+	s.Text = s.text()
+	s.Saved = time.Now()
+	shares[s.ID] = s
+	shareIds[key] = s.ID
+	sharesChanged = true
+	return s
+}
+
+func getShare(id string) (share, bool) {
+	sharesMu.Lock()
+	defer sharesMu.Unlock()
+	s, ok := shares[id]
+	if ok && time.Since(s.Saved) > shareTTL {
+		return share{}, false
+	}
+	return s, ok
+}
+
+// saveShares writes the shares once a minute when they changed.
+func saveShares() {
+	for range time.Tick(time.Minute) {
+		flushShares()
+	}
+}
+
+// flushShares drops the expired shares, then writes the others.
+func flushShares() {
+	saveMu.Lock()
+	defer saveMu.Unlock()
+	sharesMu.Lock()
+	if !sharesChanged {
+		sharesMu.Unlock()
+		return
+	}
+	snapshot := make(map[string]share)
+	for id, s := range shares {
+		if s.Saved.IsZero() {
+			s.Saved = time.Now()
+			shares[id] = s
+		}
+		if time.Since(s.Saved) > shareTTL {
+			delete(shares, id)
+			delete(shareIds, s.Key)
+			continue
+		}
+		snapshot[id] = s
+	}
+	sharesChanged = false
+	sharesMu.Unlock()
+	dump("shares", snapshot)
+}
+
+func newShare(mode string, g game, grid [][]color, day time.Time) share {
+	return share{
+		Mode:     mode,
+		Puzzle:   getPuzzleNumber(mode, day),
+		Tries:    g.guesses,
+		MaxTries: maxGuesses[mode],
+		Hints:    g.hints,
+		Won:      g.won,
+		Grid:     grid,
+	}
+}
+
+func (s share) text() string {
+	var ret strings.Builder
+	tries := "X"
+	if s.Won {
+		tries = fmt.Sprint(s.Tries)
+	}
+	ret.WriteString(fmt.Sprintf("%s #%d %s/%d", shareTitles[s.Mode], s.Puzzle, tries, s.MaxTries))
+	if s.Hints > 0 {
+		ret.WriteString(getHintsText(s.Hints))
+	}
+	ret.WriteString("\n")
+	if s.Points > 0 {
+		ret.WriteString(fmt.Sprintf("%d points\n", s.Points))
+	}
+	if s.Total > 0 {
+		ret.WriteString(fmt.Sprintf("%d/%d revealed\n", s.Revealed, s.Total))
+	}
+	for _, row := range s.Grid {
+		for _, c := range row {
+			ret.WriteString(shareEmojis[c])
+		}
+		ret.WriteString("\n")
+	}
+	// The link only exists once the share is saved.
+	if s.ID == "" {
+		ret.WriteString("sordle.net")
+	} else {
+		ret.WriteString("sordle.net/share/" + s.ID)
+	}
+	return ret.String()
+}
+
+// The composition grid has a row per club guessed, compared like in the
+// history, and a green row for the club found.
+func getCompGrid(g game, target string, clubs []clubinfos) [][]color {
+	var ret [][]color
+	t, ok := getClub(clubs, target)
+	if !ok {
+		return ret
+	}
+	for _, slug := range g.history {
+		if guess, ok := getClub(clubs, slug); ok {
+			ret = append(ret, getColors(compareClubs(t, guess)))
+		}
+	}
+	if g.won {
+		ret = append(ret, []color{GREEN, GREEN, GREEN})
+	}
+	return ret
+}
+
+// getCompRevealed counts what the reveal strategy showed on top of the
+// starting pitch before the game ended.
+func getCompRevealed(mode string, g game, games []formation) (int, int) {
+	misses := g.guesses
+	if g.won {
+		misses--
+	}
+	before, beforeBench := getCompVisibility(mode, games, 0)
+	after, afterBench := getCompVisibility(mode, games, misses)
+	revealed, total := 0, 0
+	for k := range after {
+		for l := range after[k] {
+			for i := range after[k][l] {
+				total++
+				if after[k][l][i] != before[k][l][i] {
+					revealed++
+				}
+			}
+		}
+	}
+	for i := range afterBench {
+		total++
+		if afterBench[i] != beforeBench[i] {
+			revealed++
+		}
+	}
+	return revealed, total
+}
+
+func getScoreGrid(g game, games []formation) [][]color {
+	var row []color
+	top := getTopScore(games)
+	for _, pick := range g.history {
+		if player, ok := getScorePick(games, pick); ok && player.score == top {
+			row = append(row, GREEN)
+		} else {
+			row = append(row, RED)
+		}
+	}
+	return [][]color{row}
+}
+
+func getModeUrl(mode string) string {
+	if _, ok := compModes[mode]; ok {
+		if mode == "comp" {
+			return "/comp"
+		}
+		return "/comp?mode=" + mode
+	}
+	if mode == "score" {
+		return "/score"
+	}
+	if mode == "classic" {
+		return "/classic"
+	}
+	return "/classic?mode=" + mode
+}
+
+// Link previews need absolute urls, the scheme is taken from the proxy when
+// there is one.
+func getBaseUrl(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// daily holds the puzzles fetched from Sorare. The server starts from the
+// last snapshot and refreshes it in the background, so a slow or down Sorare
+// only delays the new puzzles.
+type daily struct {
+	day    time.Time
+	comp   formation
+	season []formation
+	score  []formation
+}
+
+var puzzles daily
+var allClubs []clubinfos
+var dailyMu sync.RWMutex
+
+// gob only sees exported fields, formations are copied to these to be saved.
+type dailysnapshot struct {
+	Day    time.Time
+	Comp   formationsnapshot
+	Season []formationsnapshot
+	Score  []formationsnapshot
+}
+
+type formationsnapshot struct {
+	Name       string
+	PictureUrl string
+	Slug       string
+	Players    [][]playersnapshot
+	Bench      []playersnapshot
+	Shape      string
+	Reveal     string
+	Seed       int64
+	Match      matchsnapshot
+	Gameweek   int
+}
+
+type playersnapshot struct {
+	Score       float32
+	CountryUrl  string
+	Position    string
+	ShirtNumber int
+}
+
+type matchsnapshot struct {
+	IsHome             bool
+	OpponentName       string
+	OpponentPictureUrl string
+	GoalsFor           int
+	GoalsAgainst       int
+	Competition        string
+}
+
+func getDaily() daily {
+	dailyMu.RLock()
+	defer dailyMu.RUnlock()
+	return puzzles
+}
+
+func getClubs() []clubinfos {
+	dailyMu.RLock()
+	defer dailyMu.RUnlock()
+	return allClubs
+}
+
+func (d daily) ready() bool {
+	return d.comp.slug != "" && len(d.season) > 0 && len(d.score) == 2
+}
+
+// checks tells /readyz which puzzles are loaded, a snapshot of another day
+// isn't today's even when every puzzle is there.
+func (d daily) checks(today time.Time) map[string]bool {
+	return map[string]bool{
+		"today":  d.day.Equal(today),
+		"comp":   d.comp.slug != "" && len(d.comp.players) > 0,
+		"season": len(d.season) > 0,
+		"score":  len(d.score) == 2,
+	}
+}
+
+// warmUp serves the snapshots right away, then fetches what is missing or
+// outdated. A failed refresh keeps the last good puzzles and is tried again.
+func warmUp() {
+	if s, err := pick[dailysnapshot]("daily"); err == nil {
+		dailyMu.Lock()
+		puzzles = fromDailySnapshot(s)
+		dailyMu.Unlock()
+	}
+	if c, err := pick[[]clubinfos]("clubs"); err == nil {
+		dailyMu.Lock()
+		allClubs = c
+		dailyMu.Unlock()
+	}
+	go scheduleRefreshes()
+	for {
+		if !getDaily().day.Equal(getToday()) && !refreshDaily(getToday()) {
+			time.Sleep(5 * time.Minute)
+			continue
+		}
+		time.Sleep(time.Until(getNextRollover(time.Now())))
+	}
+}
+
+func refreshDaily(day time.Time) (ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Couldn't refresh today's puzzles", r)
+			ok = false
+		}
+	}()
+	recent := getRecentClubs(day, gamePolicy.excludeDays)
+	comp, err := getRandomGameFromLastGameweek(day, recent)
+	if err != nil {
+		log.Println("Couldn't refresh today's puzzles", err)
+		return false
+	}
+	season, err := getSeasonGames(day, compModes["season"].gameweeks, recent)
+	if err != nil {
+		log.Println("Couldn't refresh today's puzzles", err)
+		return false
+	}
+	score, err := getScoreGame(map[string]bool{comp.slug: true, season[0].slug: true})
+	if err != nil {
+		log.Println("Couldn't refresh today's puzzles", err)
+		return false
+	}
+	d := daily{
+		day:    day,
+		comp:   comp,
+		season: season,
+		score:  score,
+	}
+	if !d.ready() {
+		log.Println("Couldn't refresh today's puzzles, Sorare gave incomplete games")
+		return false
+	}
+	fmt.Println(d.comp)
+	dailyMu.Lock()
+	puzzles = d
+	dailyMu.Unlock()
+	dump("daily", toDailySnapshot(d))
+	rememberClubs(day, d.comp.slug, d.season[0].slug)
+	return true
+}
+
+func refreshClubs() bool {
+	old := getClubs()
+	c, err := getAllClubs()
+	if err == nil && len(c) == 0 {
+		err = errors.New("Sorare gave no clubs")
+	}
+	if err != nil {
+		log.Println("Couldn't refresh the clubs, keeping the last ones", err)
+		recordRefresh(refresh{Kind: "clubs", At: time.Now(), Count: len(old), Error: err.Error()})
+		return false
+	}
+	dailyMu.Lock()
+	allClubs = c
+	dailyMu.Unlock()
+	dump("clubs", c)
+	var before, after []string
+	for _, club := range old {
+		before = append(before, club.Name)
+	}
+	for _, club := range c {
+		after = append(after, club.Name)
+	}
+	added, removed := diff(before, after)
+	recordRefresh(refresh{Kind: "clubs", At: time.Now(), Count: len(c), Added: added, Removed: removed})
+	return true
+}
+
+func toDailySnapshot(d daily) dailysnapshot {
+	return dailysnapshot{
+		Day:    d.day,
+		Comp:   toFormationSnapshot(d.comp),
+		Season: toFormationSnapshots(d.season),
+		Score:  toFormationSnapshots(d.score),
+	}
+}
+
+func fromDailySnapshot(s dailysnapshot) daily {
+	return daily{
+		day:    s.Day,
+		comp:   fromFormationSnapshot(s.Comp),
+		season: fromFormationSnapshots(s.Season),
+		score:  fromFormationSnapshots(s.Score),
+	}
+}
+
+func toFormationSnapshots(formations []formation) []formationsnapshot {
+	var ret []formationsnapshot
+	for _, f := range formations {
+		ret = append(ret, toFormationSnapshot(f))
+	}
+	return ret
+}
+
+func fromFormationSnapshots(snapshots []formationsnapshot) []formation {
+	var ret []formation
+	for _, s := range snapshots {
+		ret = append(ret, fromFormationSnapshot(s))
+	}
+	return ret
+}
+
+func toFormationSnapshot(f formation) formationsnapshot {
+	ret := formationsnapshot{
+		Name:       f.name,
+		PictureUrl: f.pictureUrl,
+		Slug:       f.slug,
+		Bench:      toPlayerSnapshots(f.bench),
+		Shape:      f.shape,
+		Reveal:     f.reveal,
+		Seed:       f.seed,
+		Gameweek:   f.gameweek,
+		Match: matchsnapshot{
+			IsHome:             f.match.isHome,
+			OpponentName:       f.match.opponentName,
+			OpponentPictureUrl: f.match.opponentPictureUrl,
+			GoalsFor:           f.match.goalsFor,
+			GoalsAgainst:       f.match.goalsAgainst,
+			Competition:        f.match.competition,
+		},
+	}
+	for _, line := range f.players {
+		ret.Players = append(ret.Players, toPlayerSnapshots(line))
+	}
+	return ret
+}
+
+func fromFormationSnapshot(s formationsnapshot) formation {
+	ret := formation{
+		name:       s.Name,
+		pictureUrl: s.PictureUrl,
+		slug:       s.Slug,
+		bench:      fromPlayerSnapshots(s.Bench),
+		shape:      s.Shape,
+		reveal:     s.Reveal,
+		seed:       s.Seed,
+		gameweek:   s.Gameweek,
+		match: matchinfos{
+			isHome:             s.Match.IsHome,
+			opponentName:       s.Match.OpponentName,
+			opponentPictureUrl: s.Match.OpponentPictureUrl,
+			goalsFor:           s.Match.GoalsFor,
+			goalsAgainst:       s.Match.GoalsAgainst,
+			competition:        s.Match.Competition,
+		},
+	}
+	for _, line := range s.Players {
+		ret.players = append(ret.players, fromPlayerSnapshots(line))
+	}
+	return ret
+}
+
+func toPlayerSnapshots(players []compplayers) []playersnapshot {
+	var ret []playersnapshot
+	for _, p := range players {
+		ret = append(ret, playersnapshot{Score: p.score, CountryUrl: p.countryUrl, Position: p.position, ShirtNumber: p.shirtNumber})
+	}
+	return ret
+}
+
+func fromPlayerSnapshots(players []playersnapshot) []compplayers {
+	var ret []compplayers
+	for _, p := range players {
+		ret = append(ret, compplayers{score: p.Score, countryUrl: p.CountryUrl, position: p.Position, shirtNumber: p.ShirtNumber})
+	}
+	return ret
+}
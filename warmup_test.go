@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDailyChecks(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip("no timezone data", err)
+	}
+	today := time.Date(2026, 10, 19, 0, 0, 0, 0, paris)
+	loaded := func(day time.Time) daily {
+		return daily{
+			day:    day,
+			comp:   formation{slug: "game", players: newLineup(1, 4, 3, 3)},
+			season: newSeasonGames("flat"),
+			score:  make([]formation, 2),
+		}
+	}
+	tests := []struct {
+		name  string
+		d     daily
+		today bool
+		ready bool
+	}{
+		{"today's puzzles", loaded(today), true, true},
+		// A snapshot read back from the disk is the same day in UTC.
+		{"today's puzzles in UTC", loaded(today.UTC()), true, true},
+		{"yesterday's snapshot", loaded(today.AddDate(0, 0, -1)), false, true},
+		{"tomorrow's puzzles", loaded(today.AddDate(0, 0, 1)), false, true},
+		{"nothing loaded", daily{}, false, false},
+		{"today without the score game", daily{day: today, comp: loaded(today).comp, season: newSeasonGames("flat")}, true, false},
+	}
+	for _, tt := range tests {
+		checks := tt.d.checks(today)
+		if checks["today"] != tt.today {
+			t.Errorf("%s: today is %v, want %v", tt.name, checks["today"], tt.today)
+		}
+		ready := checks["comp"] && checks["season"] && checks["score"]
+		if ready != tt.ready {
+			t.Errorf("%s: the puzzles are loaded %v, want %v", tt.name, ready, tt.ready)
+		}
+	}
+}